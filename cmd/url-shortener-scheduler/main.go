@@ -42,7 +42,9 @@ func main() {
 
 	// queue.DispatchDetectSpamJob(qc, "")
 	queue.DispatchExpirationJob(qc)
+	queue.DispatchScheduleJob(qc)
 	queue.DispatchRemovePendingJob(qc)
+	queue.DispatchPurgeTrashJob(qc)
 
 	//loop:
 	//	for {
@@ -11,6 +11,7 @@ import (
 	_ "github.com/heroku/x/hmetrics/onload"
 	"github.com/jasontthai/tinyalias/middleware"
 	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/modules/domains"
 	"github.com/jasontthai/tinyalias/modules/queue"
 	"github.com/jasontthai/tinyalias/modules/url"
 	_ "github.com/lib/pq"
@@ -50,6 +51,11 @@ func main() {
 		log.Fatal("$SESSION_ENCRYPTION_KEY must be set")
 	}
 
+	// the ips of clicks hashed without a key are found by trying every ip
+	if os.Getenv("IP_HASH_SECRET") == "" {
+		log.Fatal("$IP_HASH_SECRET must be set")
+	}
+
 	// Que-Go
 	pgxpool, qc, err := queue.Setup(database)
 	if err != nil {
@@ -79,13 +85,13 @@ func main() {
 	router.Use(mgin.NewMiddleware(limiter.New(store, rate)))
 	router.Use(middleware.SessionStore(sessionAuthKey, sessionEncryptKey))
 	router.ForwardedByClientIP = true
+	// other sites call the API with API keys, never with the session cookie
+	// of a logged in user
 	router.Use(cors.New(cors.Config{
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Origin", "Content-Length", "Content-Type"},
-		AllowOriginFunc: func(origin string) bool {
-			return true
-		},
-		AllowCredentials: true,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowAllOrigins:  true,
+		AllowCredentials: false,
 		MaxAge:           10 * time.Minute,
 	}))
 	router.Use(gzip.Gzip(gzip.DefaultCompression))
@@ -101,12 +107,40 @@ func main() {
 
 	router.GET("", url.GetHomePage)
 	router.GET("/:slug", url.Get)
+	router.GET("/:slug/*path", url.Get)
+	router.HEAD("/:slug", url.Get)
+	router.HEAD("/:slug/*path", url.Get)
 	router.POST("/login", auth.Login)
 	router.POST("/register", auth.Register)
 	router.POST("/update-password", auth.UpdatePassword)
 	router.POST("/del", url.HandleDeleteLinks)
+	router.POST("/edit", url.HandleEditLink)
+	router.POST("/restore", url.HandleRestoreLink)
+	router.POST("/revisions", url.HandleGetLinkRevisions)
+	router.POST("/schedules", url.HandleGetSchedules)
+	router.POST("/schedules/create", url.HandleCreateSchedule)
+	router.POST("/schedules/delete", url.HandleDeleteSchedule)
 	router.POST("/get", url.HandleGetLinks)
 	router.POST("/signal", url.HandleCopySignal)
+	router.POST("/timeseries", url.HandleGetTimeSeries)
+	router.POST("/preview", url.HandlePreviewPreference)
+	router.POST("/keys", auth.HandleGetAPIKeys)
+	router.POST("/keys/create", auth.HandleCreateAPIKey)
+	router.POST("/keys/revoke", auth.HandleRevokeAPIKey)
+	router.POST("/domains", domains.HandleGetDomains)
+	router.POST("/domains/create", domains.HandleCreateDomain)
+	router.POST("/domains/verify", domains.HandleVerifyDomain)
+	router.POST("/domains/delete", domains.HandleDeleteDomain)
+	router.POST("/reserved", url.HandleGetReservedSlugs)
+	router.POST("/reserved/create", url.HandleCreateReservedSlug)
+	router.POST("/reserved/delete", url.HandleDeleteReservedSlug)
+
+	router.POST("/v2/links", url.APIV2CreateLink)
+	router.POST("/v2/links/:slug/restore", url.APIV2RestoreLink)
+	router.POST("/v2/links/:slug/schedules", url.APIV2CreateLinkSchedule)
+	router.DELETE("/v2/links/:slug/schedules/:id", url.APIV2DeleteLinkSchedule)
+	router.PATCH("/v2/links/:slug", url.APIV2UpdateLink)
+	router.DELETE("/v2/links/:slug", url.APIV2DeleteLink)
 
 	router.Run(":" + port)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net"
 	"os"
@@ -13,7 +14,11 @@ import (
 	"github.com/google/safebrowsing"
 	_ "github.com/heroku/x/hmetrics/onload"
 	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/geo"
+	"github.com/jasontthai/tinyalias/modules/metadata"
 	"github.com/jasontthai/tinyalias/modules/queue"
+	"github.com/jasontthai/tinyalias/modules/useragent"
+	"github.com/jasontthai/tinyalias/modules/utils"
 	"github.com/jasontthai/tinyalias/pg"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -25,6 +30,7 @@ import (
 var (
 	reader *geoip2.Reader
 	db     *sqlx.DB
+	qc     *que.Client
 	sb     *safebrowsing.SafeBrowser
 )
 
@@ -42,10 +48,118 @@ func RunParseGeoRequestJob(j *que.Job) error {
 
 	log.WithField("ParseGeoRequest", request).Info("Processing ParseGeoRequest!")
 
+	// jobs queued before clicks had events have no time
+	clicked := request.Clicked
+	if clicked.IsZero() {
+		clicked = j.RunAt
+	}
+	device := useragent.Parse(request.UserAgent)
+	event := models.ClickEvent{
+		Domain:    request.Domain,
+		Slug:      request.Slug,
+		Clicked:   clicked.UTC(),
+		IPHash:    request.IPHash,
+		Referrer:  request.Referrer,
+		UserAgent: request.UserAgent,
+		Language:  request.Language,
+		Variant:   request.Variant,
+		Source:    request.Source,
+		Medium:    request.Medium,
+		Campaign:  request.Campaign,
+		Browser:   device.Browser,
+		OS:        device.OS,
+		Device:    device.Device,
+		Bot:       request.Bot,
+	}
+
+	if request.Bot != "" {
+		if err := pg.UpsertURLBotStat(db, &models.URLBotStat{
+			Domain:  request.Domain,
+			Slug:    request.Slug,
+			Reason:  request.Bot,
+			Counter: 1,
+			Created: time.Now(),
+		}); err != nil {
+			log.WithFields(log.Fields{
+				"slug": request.Slug,
+				"bot":  request.Bot,
+			}).WithError(err).Error("Error Saving Bot Stat")
+		}
+		// bot hits are kept out of the stats of human clicks
+		if !request.Counted {
+			recordClickEvent(event)
+			return nil
+		}
+	}
+
+	if request.Variant != "" {
+		if err := pg.UpsertURLVariantStat(db, &models.URLVariantStat{
+			Domain:  request.Domain,
+			Slug:    request.Slug,
+			Variant: request.Variant,
+			Counter: 1,
+			Created: time.Now(),
+		}); err != nil {
+			log.WithFields(log.Fields{
+				"slug":    request.Slug,
+				"variant": request.Variant,
+			}).WithError(err).Error("Error Saving Variant Stat")
+		}
+	}
+
+	if request.Source != "" {
+		if err := pg.UpsertURLSourceStat(db, &models.URLSourceStat{
+			Domain:  request.Domain,
+			Slug:    request.Slug,
+			Source:  request.Source,
+			Medium:  request.Medium,
+			Counter: 1,
+			Created: time.Now(),
+		}); err != nil {
+			log.WithFields(log.Fields{
+				"slug":   request.Slug,
+				"source": request.Source,
+			}).WithError(err).Error("Error Saving Source Stat")
+		}
+	}
+
+	if request.Campaign != "" {
+		if err := pg.UpsertURLCampaignStat(db, &models.URLCampaignStat{
+			Domain:   request.Domain,
+			Slug:     request.Slug,
+			Campaign: request.Campaign,
+			Counter:  1,
+			Created:  time.Now(),
+		}); err != nil {
+			log.WithFields(log.Fields{
+				"slug":     request.Slug,
+				"campaign": request.Campaign,
+			}).WithError(err).Error("Error Saving Campaign Stat")
+		}
+	}
+
+	if err := pg.UpsertURLDeviceStat(db, &models.URLDeviceStat{
+		Domain:  request.Domain,
+		Slug:    request.Slug,
+		Browser: device.Browser,
+		OS:      device.OS,
+		Device:  device.Device,
+		Counter: 1,
+		Created: time.Now(),
+	}); err != nil {
+		log.WithFields(log.Fields{
+			"slug":       request.Slug,
+			"user_agent": request.UserAgent,
+		}).WithError(err).Error("Error Saving Device Stat")
+	}
+
+	// every forwarded ip counts in the aggregates, the event of the click
+	// is located by the first one
+	var country, state string
 	ips := strings.Split(request.IP, ",")
-	for _, ip := range ips {
+	for i, ip := range ips {
 		slug := request.Slug
-		record, err := reader.City(net.ParseIP(ip))
+		record, err := reader.City(net.ParseIP(strings.TrimSpace(ip)))
 		if err != nil {
 			log.WithFields(log.Fields{
 				"ip":   ip,
@@ -53,15 +167,19 @@ func RunParseGeoRequestJob(j *que.Job) error {
 			}).WithError(err).Error("Error Getting Geo Info")
 		}
 
-		var state string
+		var recordState string
 		if len(record.Subdivisions) != 0 {
-			state = record.Subdivisions[0].Names["en"]
+			recordState = record.Subdivisions[0].Names["en"]
+		}
+		if i == 0 {
+			country, state = record.Country.Names["en"], recordState
 		}
 
 		if err = pg.UpsertURLStat(db, &models.URLStat{
+			Domain:  request.Domain,
 			Slug:    slug,
 			Country: record.Country.Names["en"],
-			State:   state,
+			State:   recordState,
 			Counter: 1,
 			Created: time.Now(),
 		}); err != nil {
@@ -72,6 +190,17 @@ func RunParseGeoRequestJob(j *que.Job) error {
 		}
 	}
 
+	if err := pg.UpsertURLHourlyStat(db, &models.URLHourlyStat{
+		Domain:  request.Domain,
+		Slug:    request.Slug,
+		Hour:    clicked,
+		Counter: 1,
+	}); err != nil {
+		log.WithField("slug", request.Slug).WithError(err).Error("Error Saving Hourly Stat")
+	}
+	event.Country, event.State = country, state
+	recordClickEvent(event)
+
 	return nil
 }
 
@@ -107,9 +236,24 @@ func RunDetectSpamJob(j *que.Job) error {
 			break
 		}
 
+		// targeted destinations are scanned together with the url
 		var urlStr []string
-		for _, url := range urls {
+		var owners []int
+		for i, url := range urls {
 			urlStr = append(urlStr, url.Url)
+			owners = append(owners, i)
+			for _, rule := range url.Targets {
+				urlStr = append(urlStr, rule.URL)
+				owners = append(owners, i)
+			}
+			for _, rule := range url.GeoTargets {
+				urlStr = append(urlStr, rule.URL)
+				owners = append(owners, i)
+			}
+			for _, variant := range url.Variants {
+				urlStr = append(urlStr, variant.URL)
+				owners = append(owners, i)
+			}
 		}
 
 		threats, err := sb.LookupURLs(urlStr)
@@ -118,14 +262,28 @@ func RunDetectSpamJob(j *que.Job) error {
 			return err
 		}
 
+		urlThreats := make([][]safebrowsing.URLThreat, len(urls))
+		for i, threat := range threats {
+			if len(threat) > 0 && len(urlThreats[owners[i]]) == 0 {
+				urlThreats[owners[i]] = threat
+			}
+		}
+
 		for i, url := range urls {
-			if len(threats[i]) > 0 {
+			if len(urlThreats[i]) > 0 {
 				// Detected link as threat - only need to get the first threat type
-				url.Status = threats[i][0].ThreatType.String()
+				oldStatus := url.Status
+				url.Status = urlThreats[i][0].ThreatType.String()
+				if oldStatus == url.Status {
+					continue
+				}
 				err = pg.UpdateURL(db, &url)
 				if err != nil {
 					log.WithError(err).Error("Error updating url")
+					continue
 				}
+				recordRevision(models.RevisionStatus, url,
+					models.PropertyMap{"status": oldStatus}, models.PropertyMap{"status": url.Status})
 			}
 		}
 	}
@@ -133,12 +291,80 @@ func RunDetectSpamJob(j *que.Job) error {
 	return nil
 }
 
+// RunScheduleJob applies the scheduled destination changes that are due, in
+// the order they were scheduled
+func RunScheduleJob(j *que.Job) error {
+	log.Info("Running Schedule Job")
+	schedules, err := pg.GetURLSchedules(db, map[string]interface{}{
+		"pending": true,
+		"due":     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		url, err := pg.ApplyURLSchedule(db, &schedule)
+		if err != nil {
+			log.WithField("schedule", schedule.ID).WithError(err).Error("Error applying schedule")
+			continue
+		}
+		if url == nil || url.Url == schedule.URL {
+			continue
+		}
+
+		log.WithField("slug", url.Slug).WithField("url", schedule.URL).Info("Applied scheduled destination")
+		recordRevision(models.RevisionSchedule, *url,
+			models.PropertyMap{"url": url.Url}, models.PropertyMap{"url": schedule.URL})
+		if err := queue.DispatchDetectSpamJob(qc, schedule.URL); err != nil {
+			log.WithField("url", schedule.URL).WithError(err).Error("error sending spam detect job")
+		}
+		if err := queue.DispatchFetchMetadataJob(qc, url.Domain, url.Slug); err != nil {
+			log.WithField("slug", url.Slug).WithError(err).Error("error sending fetch metadata job")
+		}
+	}
+	return nil
+}
+
+// RunFetchMetadataJob stores the title, description, favicon and image of
+// the destination of a url. Destinations flagged by the spam job are not
+// fetched.
+func RunFetchMetadataJob(j *que.Job) error {
+	var request queue.FetchMetadataRequest
+	if err := json.Unmarshal(j.Args, &request); err != nil {
+		return errors.Wrap(err, "Unable to unmarshal job arguments into FetchMetadataRequest: "+string(j.Args))
+	}
+
+	url, err := pg.GetURL(db, request.Domain, request.Slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if url.Deleted.Valid || !url.IsSafe() {
+		return nil
+	}
+
+	log.Info("Fetching metadata of: ", url.Url)
+	page, err := metadata.Fetch(url.Url)
+	if err != nil {
+		// pages that cannot be fetched are not retried, they keep no metadata
+		log.WithField("url", url.Url).WithError(err).Warn("Error fetching metadata")
+		return nil
+	}
+	return pg.UpdateURLMetadata(db, url.Domain, url.Slug, *page)
+}
+
 func RunExpirationJob(j *que.Job) error {
 	log.Info("Running Expiration Job")
-	_, err := db.Exec("UPDATE urls SET status = 'expired' WHERE expired IS NOT NULL AND expired < NOW()")
+	urls, err := pg.ExpireURLs(db)
 	if err != nil {
 		return err
 	}
+	for _, url := range urls {
+		recordRevision(models.RevisionStatus, url,
+			models.PropertyMap{"status": url.Status}, models.PropertyMap{"status": models.Expired})
+	}
 
 	//log.Info("Running Delete Job")
 	//_, err = db.Exec("DELETE from urls WHERE created < CURRENT_DATE - interval '3' day")
@@ -148,8 +374,55 @@ func RunExpirationJob(j *que.Job) error {
 
 func RunRemovePendingJob(j *que.Job) error {
 	log.Info("Running Remove Pending Job")
-	_, err := db.Exec("DELETE FROM urls WHERE status = 'pending'")
-	return err
+	urls, err := pg.DeletePendingURLs(db)
+	if err != nil {
+		return err
+	}
+	// like purged urls, their revisions are gone with them
+	for _, url := range urls {
+		log.WithField("slug", url.Slug).WithField("domain", url.Domain).Info("Removed pending URL")
+	}
+	return nil
+}
+
+func RunPurgeTrashJob(j *que.Job) error {
+	log.Info("Running Purge Trash Job")
+	urls, err := pg.PurgeTrashedURLs(db, time.Now().Add(-utils.TrashRetention))
+	if err != nil {
+		return err
+	}
+	// the revisions of purged urls are gone with them, so the purge is only
+	// logged
+	for _, url := range urls {
+		log.WithField("slug", url.Slug).WithField("domain", url.Domain).Info("Purged URL")
+	}
+	log.WithField("count", len(urls)).Info("Purged URLs from trash")
+	return nil
+}
+
+// recordClickEvent adds a click to the log of clicks
+func recordClickEvent(event models.ClickEvent) {
+	if err := pg.CreateClickEvent(db, &event); err != nil {
+		log.WithField("slug", event.Slug).WithError(err).Error("Error Saving Click Event")
+	}
+}
+
+// recordRevision adds an entry made by the worker to the audit trail of a url
+func recordRevision(action string, url models.URL, oldValues, newValues models.PropertyMap) {
+	if err := pg.CreateURLRevision(db, &models.URLRevision{
+		Domain:    url.Domain,
+		Slug:      url.Slug,
+		Action:    action,
+		Source:    models.SourceWorker,
+		OldValues: oldValues,
+		NewValues: newValues,
+		Created:   time.Now(),
+	}); err != nil {
+		log.WithField("slug", url.Slug).
+			WithField("domain", url.Domain).
+			WithField("action", action).
+			WithError(err).Error("Error recording url revision")
+	}
 }
 
 func main() {
@@ -158,17 +431,22 @@ func main() {
 		log.Fatal("$DATABASE_URL must be set")
 	}
 
-	pgxpool, qc, err := queue.Setup(databaseURL)
+	// the ips of clicks hashed without a key are found by trying every ip
+	if os.Getenv("IP_HASH_SECRET") == "" {
+		log.Fatal("$IP_HASH_SECRET must be set")
+	}
+
+	pgxpool, client, err := queue.Setup(databaseURL)
 	if err != nil {
 		log.Fatal("error initializing que-go")
 	}
 	defer pgxpool.Close()
+	qc = client
 
-	reader, err = geoip2.Open("static/GeoLite2-City.mmdb")
+	reader, err = geo.Reader()
 	if err != nil {
 		log.Fatal("error initializing geoip2")
 	}
-	defer reader.Close()
 
 	db, err = sqlx.Open("postgres", databaseURL)
 	if err != nil {
@@ -190,13 +468,16 @@ func main() {
 		queue.DetectSpamJob:      RunDetectSpamJob,
 		queue.ExpirationJob:      RunExpirationJob,
 		queue.RemovePendingJob:   RunRemovePendingJob,
+		queue.PurgeTrashJob:      RunPurgeTrashJob,
+		queue.ScheduleJob:        RunScheduleJob,
+		queue.FetchMetadataJob:   RunFetchMetadataJob,
 	}
 
 	// 1 worker go routine
 	workers := que.NewWorkerPool(qc, wm, 1)
 
 	// Catch signal so we can shutdown gracefully
-	sigCh := make(chan os.Signal)
+	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
 
 	go workers.Start()
@@ -33,11 +33,12 @@ require (
 	github.com/pkg/errors v0.8.1-0.20180911062113-c059e472caf7
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.0.7-0.20180908091816-f3df9aeffda7
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.2.3-0.20181002233221-2db35c88b92a
 	github.com/ugorji/go v1.1.7 // indirect
 	github.com/ulule/limiter v2.2.1-0.20180910083227-1f43cf9e49e5+incompatible
 	golang.org/x/crypto v0.0.0-20181001203147-e3636079e1a4
-	golang.org/x/net v0.0.0-20180926154720-4dfa2610cdf3 // indirect
+	golang.org/x/net v0.0.0-20180926154720-4dfa2610cdf3
 	golang.org/x/sys v0.0.0-20180909124046-d0be0721c37e // indirect
 	golang.org/x/text v0.3.1-0.20180911161511-905a57155faa // indirect
 	gopkg.in/go-playground/validator.v8 v8.18.2 // indirect
@@ -5,3 +5,46 @@ type Analytics struct {
 	Country string `json:"country"`
 	Count   int    `json:"count"`
 }
+
+// VariantAnalytics are the clicks that went to a variant of an A/B link
+type VariantAnalytics struct {
+	Variant string `json:"variant"`
+	Count   int    `json:"count"`
+}
+
+// SourceAnalytics are the clicks that came from a source, e.g. QR code scans
+// or twitter
+type SourceAnalytics struct {
+	Source string `json:"source"`
+	Medium string `json:"medium"`
+	Count  int    `json:"count"`
+}
+
+// CampaignAnalytics are the clicks tagged with a utm_campaign
+type CampaignAnalytics struct {
+	Campaign string `json:"campaign"`
+	Count    int    `json:"count"`
+}
+
+// BotAnalytics are the hits of bots told apart by a reason, e.g. their
+// User-Agent
+type BotAnalytics struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// UserAgentAnalytics are the clicks from a browser, OS or device class and
+// their percentage of all clicks
+type UserAgentAnalytics struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// DeviceAnalytics break the clicks of a url down by device class, OS and
+// browser, most clicked first
+type DeviceAnalytics struct {
+	Devices  []UserAgentAnalytics `json:"devices"`
+	OS       []UserAgentAnalytics `json:"os"`
+	Browsers []UserAgentAnalytics `json:"browsers"`
+}
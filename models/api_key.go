@@ -0,0 +1,82 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+)
+
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeStatsRead  = "stats:read"
+
+	apiKeyPrefix = "ta_"
+	apiKeySize   = 32
+)
+
+var Scopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+// APIKey is a personal API key. Only the hash of the key is stored, the key
+// itself is shown to the user once when it is created.
+type APIKey struct {
+	ID       int64          `json:"id" db:"id"`
+	Username string         `json:"username" db:"username"`
+	Name     string         `json:"name" db:"name"`
+	Prefix   string         `json:"prefix" db:"prefix"`
+	Hash     string         `json:"-" db:"hash"`
+	Scopes   pq.StringArray `json:"scopes" db:"scopes"`
+	Created  time.Time      `json:"created" db:"created"`
+	LastUsed null.Time      `json:"last_used" db:"last_used"`
+	Revoked  null.Time      `json:"revoked" db:"revoked"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIKey returns a new random API key using crypto/rand
+func GenerateAPIKey() (string, error) {
+	key := apiKeyPrefix
+	max := big.NewInt(int64(len(base)))
+	for i := 0; i < apiKeySize; i++ {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		key = key + string(base[idx.Int64()])
+	}
+	return key, nil
+}
+
+// APIKeyPrefix returns the part of the key that is safe to display
+func APIKeyPrefix(key string) string {
+	if len(key) < len(apiKeyPrefix)+6 {
+		return key
+	}
+	return key[:len(apiKeyPrefix)+6]
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
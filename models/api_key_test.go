@@ -0,0 +1,30 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, err := GenerateAPIKey()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, "ta_"))
+	assert.Equal(t, 35, len(key))
+
+	other, err := GenerateAPIKey()
+	assert.Nil(t, err)
+	assert.NotEqual(t, key, other)
+
+	assert.Equal(t, HashAPIKey(key), HashAPIKey(key))
+	assert.NotEqual(t, HashAPIKey(key), HashAPIKey(other))
+	assert.Equal(t, key[:9], APIKeyPrefix(key))
+
+	apiKey := &APIKey{Scopes: []string{ScopeLinksRead}}
+	assert.True(t, apiKey.HasScope(ScopeLinksRead))
+	assert.False(t, apiKey.HasScope(ScopeLinksWrite))
+
+	assert.True(t, IsValidScope(ScopeStatsRead))
+	assert.False(t, IsValidScope("links:admin"))
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	url2 "net/url"
+	"strings"
+)
+
+const (
+	MaxCardTitleLength       = 200
+	MaxCardDescriptionLength = 500
+	MaxCardImageLength       = 2048
+)
+
+// unfurlBots are found in the User-Agent of the bots chat apps and social
+// networks send to build the preview of a pasted link
+var unfurlBots = []string{
+	"facebookexternalhit",
+	"facebot",
+	"twitterbot",
+	"slackbot",
+	"slack-imgproxy",
+	"linkedinbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"skypeuripreview",
+	"microsoftpreview",
+	"pinterestbot",
+	"redditbot",
+	"embedly",
+	"mastodon",
+	"vkshare",
+	"snapchat",
+	"iframely",
+}
+
+// Card is the OpenGraph and Twitter card a url shows when its short link is
+// pasted in a chat app
+type Card struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Image is an absolute http(s) url
+	Image string `json:"image,omitempty"`
+}
+
+// IsEmpty tells whether the owner of the url set no card
+func (c Card) IsEmpty() bool {
+	return c.Title == "" && c.Description == "" && c.Image == ""
+}
+
+func (c Card) IsValid() bool {
+	if len([]rune(c.Title)) > MaxCardTitleLength || len([]rune(c.Description)) > MaxCardDescriptionLength {
+		return false
+	}
+	if c.Image == "" {
+		return true
+	}
+	image, err := url2.Parse(c.Image)
+	return err == nil && len(c.Image) <= MaxCardImageLength &&
+		(image.Scheme == "http" || image.Scheme == "https") && image.Host != ""
+}
+
+// IsUnfurlBot tells whether a User-Agent belongs to a bot building the
+// preview of a link
+func IsUnfurlBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, bot := range unfurlBots {
+		if strings.Contains(userAgent, bot) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value marshals the card to JSONB
+func (c Card) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan unmarshals the card from JSONB
+func (c *Card) Scan(src interface{}) error {
+	if src == nil {
+		*c = Card{}
+		return nil
+	}
+	source, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("Type assertion .([]byte) failed.")
+	}
+	return json.Unmarshal(source, c)
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCard(t *testing.T) {
+	assert.True(t, Card{}.IsEmpty())
+	assert.True(t, Card{}.IsValid())
+	assert.False(t, Card{Image: "https://example.com/card.png"}.IsEmpty())
+
+	assert.True(t, Card{Title: "Launch", Image: "https://example.com/card.png"}.IsValid())
+	assert.False(t, Card{Image: "/card.png"}.IsValid())
+	assert.False(t, Card{Image: "javascript:alert(1)"}.IsValid())
+	assert.False(t, Card{Title: strings.Repeat("a", MaxCardTitleLength+1)}.IsValid())
+	assert.False(t, Card{Description: strings.Repeat("a", MaxCardDescriptionLength+1)}.IsValid())
+}
+
+func TestIsUnfurlBot(t *testing.T) {
+	assert.True(t, IsUnfurlBot("Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"))
+	assert.True(t, IsUnfurlBot("facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)"))
+	assert.True(t, IsUnfurlBot("Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)"))
+	assert.True(t, IsUnfurlBot("Twitterbot/1.0"))
+	assert.True(t, IsUnfurlBot("WhatsApp/2.19.81 A"))
+	assert.False(t, IsUnfurlBot("Mozilla/5.0 (iPhone; CPU iPhone OS 12_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"))
+	assert.False(t, IsUnfurlBot(""))
+}
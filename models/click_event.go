@@ -0,0 +1,60 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	MaxReferrerLength  = 2048
+	MaxUserAgentLength = 512
+	MaxLanguageLength  = 35
+)
+
+// ClickEvent is a single click of a url. The ip of the visitor is only kept
+// hashed.
+type ClickEvent struct {
+	ID        int64     `json:"id" db:"id"`
+	Domain    string    `json:"domain" db:"domain"`
+	Slug      string    `json:"slug" db:"slug"`
+	Clicked   time.Time `json:"clicked" db:"clicked"`
+	IPHash    string    `json:"ip_hash" db:"ip_hash"`
+	Referrer  string    `json:"referrer" db:"referrer"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	Language  string    `json:"language" db:"language"`
+	Variant   string    `json:"variant" db:"variant"`
+	Source    string    `json:"source" db:"source"`
+	Medium    string    `json:"medium" db:"medium"`
+	Campaign  string    `json:"campaign" db:"campaign"`
+	Browser   string    `json:"browser" db:"browser"`
+	OS        string    `json:"os" db:"os"`
+	Device    string    `json:"device" db:"device"`
+	Bot       string    `json:"bot" db:"bot"`
+	Country   string    `json:"country" db:"country"`
+	State     string    `json:"state" db:"state"`
+}
+
+// HashIP keys an ip with secret so clicks of a visitor can be told apart
+// without storing where they came from
+func HashIP(ip, secret string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PrimaryLanguage returns the first language of an Accept-Language header,
+// e.g. en-US for "en-US,en;q=0.9"
+func PrimaryLanguage(acceptLanguage string) string {
+	language := strings.SplitN(acceptLanguage, ",", 2)[0]
+	language = strings.TrimSpace(strings.SplitN(language, ";", 2)[0])
+	if language == "*" || len(language) > MaxLanguageLength {
+		return ""
+	}
+	return language
+}
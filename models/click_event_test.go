@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashIP(t *testing.T) {
+	hash := HashIP("203.0.113.7", "secret")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashIP("203.0.113.7", "secret"))
+	assert.NotEqual(t, hash, HashIP("203.0.113.8", "secret"))
+	assert.NotEqual(t, hash, HashIP("203.0.113.7", "other"))
+	assert.Equal(t, "", HashIP("", "secret"))
+}
+
+func TestPrimaryLanguage(t *testing.T) {
+	assert.Equal(t, "en-US", PrimaryLanguage("en-US,en;q=0.9,de;q=0.8"))
+	assert.Equal(t, "de", PrimaryLanguage("de;q=0.9"))
+	assert.Equal(t, "fr", PrimaryLanguage(" fr "))
+	assert.Equal(t, "", PrimaryLanguage("*"))
+	assert.Equal(t, "", PrimaryLanguage(""))
+}
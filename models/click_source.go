@@ -0,0 +1,154 @@
+package models
+
+import (
+	url2 "net/url"
+	"strings"
+)
+
+const (
+	// SourceDirect is the source of clicks without a referrer or campaign
+	SourceDirect = "direct"
+
+	MediumNone     = "none"
+	MediumSocial   = "social"
+	MediumSearch   = "search"
+	MediumEmail    = "email"
+	MediumReferral = "referral"
+	MediumQR       = "qr"
+
+	// MaxCampaignLength is how long the utm values of a click may be
+	MaxCampaignLength = 100
+)
+
+// ClickSource is where a click came from. Campaign is only set by utm
+// parameters.
+type ClickSource struct {
+	Source   string
+	Medium   string
+	Campaign string
+}
+
+type knownSource struct {
+	domain string
+	source string
+	medium string
+}
+
+// knownSources name the referrers we recognize. Domains match their
+// subdomains too and the first match wins, so mail hosts come before the
+// search engines of the same company.
+var knownSources = []knownSource{
+	{"mail.google.com", "gmail", MediumEmail},
+	{"outlook.live.com", "outlook", MediumEmail},
+	{"outlook.office.com", "outlook", MediumEmail},
+	{"outlook.office365.com", "outlook", MediumEmail},
+	{"mail.yahoo.com", "yahoo mail", MediumEmail},
+	{"mail.proton.me", "proton mail", MediumEmail},
+
+	{"t.co", "twitter", MediumSocial},
+	{"twitter.com", "twitter", MediumSocial},
+	{"x.com", "twitter", MediumSocial},
+	{"facebook.com", "facebook", MediumSocial},
+	{"fb.com", "facebook", MediumSocial},
+	{"fb.me", "facebook", MediumSocial},
+	{"messenger.com", "facebook", MediumSocial},
+	{"instagram.com", "instagram", MediumSocial},
+	{"linkedin.com", "linkedin", MediumSocial},
+	{"lnkd.in", "linkedin", MediumSocial},
+	{"reddit.com", "reddit", MediumSocial},
+	{"news.ycombinator.com", "hacker news", MediumSocial},
+	{"youtube.com", "youtube", MediumSocial},
+	{"youtu.be", "youtube", MediumSocial},
+	{"pinterest.com", "pinterest", MediumSocial},
+	{"tiktok.com", "tiktok", MediumSocial},
+	{"tumblr.com", "tumblr", MediumSocial},
+	{"quora.com", "quora", MediumSocial},
+	{"slack.com", "slack", MediumSocial},
+	{"discord.com", "discord", MediumSocial},
+	{"discordapp.com", "discord", MediumSocial},
+	{"t.me", "telegram", MediumSocial},
+	{"web.telegram.org", "telegram", MediumSocial},
+	{"whatsapp.com", "whatsapp", MediumSocial},
+	{"vk.com", "vk", MediumSocial},
+
+	{"bing.com", "bing", MediumSearch},
+	{"duckduckgo.com", "duckduckgo", MediumSearch},
+	{"search.yahoo.com", "yahoo", MediumSearch},
+	{"baidu.com", "baidu", MediumSearch},
+	{"ecosia.org", "ecosia", MediumSearch},
+	{"search.brave.com", "brave", MediumSearch},
+}
+
+// searchEngines are recognized on any of their country domains, e.g.
+// google.co.uk
+var searchEngines = []string{"google", "yandex"}
+
+// ParseClickSource tells where a click came from by its utm parameters, or
+// else by the host of its referrer. Referrers from host, where the short
+// link is served, count as direct clicks.
+func ParseClickSource(referrer string, utm url2.Values, host string) ClickSource {
+	if source := normalizeCampaign(utm.Get("utm_source")); source != "" {
+		medium := normalizeCampaign(utm.Get("utm_medium"))
+		if medium == "" {
+			medium = MediumReferral
+		}
+		return ClickSource{
+			Source:   source,
+			Medium:   medium,
+			Campaign: normalizeCampaign(utm.Get("utm_campaign")),
+		}
+	}
+
+	parsed, err := url2.Parse(strings.TrimSpace(referrer))
+	if err != nil || parsed.Hostname() == "" {
+		return ClickSource{Source: SourceDirect, Medium: MediumNone}
+	}
+	referrerHost := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if host != "" && referrerHost == strings.TrimPrefix(strings.ToLower(stripPort(host)), "www.") {
+		return ClickSource{Source: SourceDirect, Medium: MediumNone}
+	}
+
+	for _, known := range knownSources {
+		if referrerHost == known.domain || strings.HasSuffix(referrerHost, "."+known.domain) {
+			return ClickSource{Source: known.source, Medium: known.medium}
+		}
+	}
+	labels := strings.Split(referrerHost, ".")
+	for i, label := range labels {
+		for _, engine := range searchEngines {
+			if label == engine && isPublicSuffix(labels[i+1:]) {
+				return ClickSource{Source: engine, Medium: MediumSearch}
+			}
+		}
+	}
+	return ClickSource{Source: referrerHost, Medium: MediumReferral}
+}
+
+// normalizeCampaign lowercases a utm value so differently cased tags are
+// counted together
+func normalizeCampaign(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if runes := []rune(value); len(runes) > MaxCampaignLength {
+		value = string(runes[:MaxCampaignLength])
+	}
+	return value
+}
+
+// isPublicSuffix tells whether labels look like a tld such as com, de or
+// co.uk
+func isPublicSuffix(labels []string) bool {
+	switch len(labels) {
+	case 1:
+		return labels[0] != ""
+	case 2:
+		return len(labels[0]) <= 3 && len(labels[1]) == 2
+	}
+	return false
+}
+
+func stripPort(host string) string {
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		return host[:i]
+	}
+	return host
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	url2 "net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClickSource(t *testing.T) {
+	for referrer, expected := range map[string]ClickSource{
+		"":                                       {Source: SourceDirect, Medium: MediumNone},
+		"not a url":                              {Source: SourceDirect, Medium: MediumNone},
+		"https://tinyalias.com/abc+":             {Source: SourceDirect, Medium: MediumNone},
+		"https://t.co/abc":                       {Source: "twitter", Medium: MediumSocial},
+		"https://l.facebook.com/l.php?u=x":       {Source: "facebook", Medium: MediumSocial},
+		"https://www.google.co.uk/":              {Source: "google", Medium: MediumSearch},
+		"https://www.google.com/search?q=x":      {Source: "google", Medium: MediumSearch},
+		"https://mail.google.com/mail/u/0/":      {Source: "gmail", Medium: MediumEmail},
+		"https://news.ycombinator.com/item?id=1": {Source: "hacker news", Medium: MediumSocial},
+		"https://www.example.com/blog":           {Source: "example.com", Medium: MediumReferral},
+		"https://google.example.com/":            {Source: "google.example.com", Medium: MediumReferral},
+	} {
+		assert.Equal(t, expected, ParseClickSource(referrer, url2.Values{}, "tinyalias.com:443"), referrer)
+	}
+
+	source := ParseClickSource("https://t.co/abc", url2.Values{
+		"utm_source":   {" Newsletter "},
+		"utm_medium":   {"Email"},
+		"utm_campaign": {"Spring-Sale"},
+	}, "tinyalias.com")
+	assert.Equal(t, ClickSource{Source: "newsletter", Medium: "email", Campaign: "spring-sale"}, source)
+
+	source = ParseClickSource("", url2.Values{"utm_source": {"partner"}}, "tinyalias.com")
+	assert.Equal(t, ClickSource{Source: "partner", Medium: MediumReferral}, source)
+}
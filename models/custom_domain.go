@@ -0,0 +1,56 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/guregu/null"
+)
+
+const (
+	// DomainVerificationRecord is the name of the TXT record proving
+	// ownership of a custom domain, prepended to the domain
+	DomainVerificationRecord = "_tinyalias"
+	domainTokenPrefix        = "tinyalias-verification="
+)
+
+var hostRegexp = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// CustomDomain is a domain of a user serving their links once verified
+type CustomDomain struct {
+	Host     string    `json:"host" db:"host"`
+	Username string    `json:"username" db:"username"`
+	Token    string    `json:"token" db:"token"`
+	Verified null.Time `json:"verified" db:"verified"`
+	Created  time.Time `json:"created" db:"created"`
+}
+
+// VerificationRecord returns the TXT record name and value the owner has to add
+func (d *CustomDomain) VerificationRecord() (string, string) {
+	return fmt.Sprintf("%v.%v", DomainVerificationRecord, d.Host), domainTokenPrefix + d.Token
+}
+
+func GenerateDomainToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NormalizeHost lowercases a host and strips its port
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+func IsValidHost(host string) bool {
+	return hostRegexp.MatchString(host)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	assert.Equal(t, "go.example.com", NormalizeHost("Go.Example.com"))
+	assert.Equal(t, "go.example.com", NormalizeHost("go.example.com:8080"))
+	assert.Equal(t, "go.example.com", NormalizeHost("go.example.com."))
+}
+
+func TestIsValidHost(t *testing.T) {
+	assert.True(t, IsValidHost("go.example.com"))
+	assert.True(t, IsValidHost("my-links.io"))
+	assert.False(t, IsValidHost("localhost"))
+	assert.False(t, IsValidHost("-bad.example.com"))
+	assert.False(t, IsValidHost("example.com/path"))
+}
+
+func TestVerificationRecord(t *testing.T) {
+	token, err := GenerateDomainToken()
+	assert.Nil(t, err)
+	assert.Len(t, token, 32)
+
+	domain := CustomDomain{Host: "go.example.com", Token: token}
+	name, value := domain.VerificationRecord()
+	assert.Equal(t, "_tinyalias.go.example.com", name)
+	assert.Equal(t, "tinyalias-verification="+token, value)
+}
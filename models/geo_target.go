@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	countryRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+	regionRegexp  = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+)
+
+// GeoRule sends the visitors from a country, or from a region of it, to
+// another destination. Country is an ISO 3166-1 alpha-2 code and Region the
+// ISO 3166-2 subdivision code without the country, e.g. DE and BY for Bavaria.
+type GeoRule struct {
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+	URL     string `json:"url"`
+}
+
+// GeoRules are evaluated in order, the first matching rule wins
+type GeoRules []GeoRule
+
+// Normalize upper-cases the codes of the rule
+func (r GeoRule) Normalize() GeoRule {
+	r.Country = strings.ToUpper(strings.TrimSpace(r.Country))
+	r.Region = strings.ToUpper(strings.TrimSpace(r.Region))
+	return r
+}
+
+func (r GeoRule) IsValid() bool {
+	return countryRegexp.MatchString(r.Country) && (r.Region == "" || regionRegexp.MatchString(r.Region))
+}
+
+// Matches tells whether the rule applies to a location. Unknown locations
+// never match.
+func (r GeoRule) Matches(country, region string) bool {
+	if country == "" || r.Country != country {
+		return false
+	}
+	return r.Region == "" || r.Region == region
+}
+
+// String lists the rules in order, e.g. "US-CA: https://example.com/ca"
+func (g GeoRules) String() string {
+	rules := make([]string, 0, len(g))
+	for _, rule := range g {
+		location := rule.Country
+		if rule.Region != "" {
+			location += "-" + rule.Region
+		}
+		rules = append(rules, fmt.Sprintf("%v: %v", location, rule.URL))
+	}
+	return strings.Join(rules, ", ")
+}
+
+// Value marshals the rules to JSONB
+func (g GeoRules) Value() (driver.Value, error) {
+	if g == nil {
+		g = GeoRules{}
+	}
+	return json.Marshal(g)
+}
+
+// Scan unmarshals the rules from JSONB
+func (g *GeoRules) Scan(src interface{}) error {
+	if src == nil {
+		*g = nil
+		return nil
+	}
+	source, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("Type assertion .([]byte) failed.")
+	}
+	return json.Unmarshal(source, g)
+}
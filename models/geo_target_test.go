@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoRule(t *testing.T) {
+	rule := GeoRule{Country: " de ", Region: "by"}.Normalize()
+	assert.Equal(t, GeoRule{Country: "DE", Region: "BY"}, rule)
+	assert.True(t, rule.IsValid())
+	assert.False(t, GeoRule{Country: "DEU"}.IsValid())
+	assert.False(t, GeoRule{Country: "DE", Region: "BY-1"}.IsValid())
+
+	assert.True(t, rule.Matches("DE", "BY"))
+	assert.False(t, rule.Matches("DE", "BE"))
+	assert.True(t, GeoRule{Country: "DE"}.Matches("DE", ""))
+	assert.False(t, GeoRule{Country: "DE"}.Matches("", ""))
+}
+
+func TestGeoRules(t *testing.T) {
+	rules := GeoRules{{Country: "US", Region: "CA", URL: "https://example.com/ca"}, {Country: "DE", URL: "https://example.de"}}
+	assert.Equal(t, "US-CA: https://example.com/ca, DE: https://example.de", rules.String())
+
+	value, err := rules.Value()
+	assert.Nil(t, err)
+	var scanned GeoRules
+	assert.Nil(t, scanned.Scan(value))
+	assert.Equal(t, rules, scanned)
+}
+
+func TestTargetGeo(t *testing.T) {
+	url := &URL{
+		Url:        "https://example.com",
+		Targets:    TargetRules{{Platform: PlatformIOS, URL: "https://apps.apple.com/app/example"}},
+		GeoTargets: GeoRules{{Country: "DE", URL: "https://example.de"}},
+	}
+	iPhone := "Mozilla/5.0 (iPhone; CPU iPhone OS 12_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+
+	assert.Equal(t, "https://example.de", url.Target(Visitor{Country: "DE"}))
+	assert.Equal(t, "https://example.com", url.Target(Visitor{Country: "FR"}))
+	// unknown locations fall back to the url
+	assert.Equal(t, "https://example.com", url.Target(Visitor{}))
+	url.Variants = Variants{{Name: "B", URL: "https://example.com/b", Weight: 1}}
+	assert.Equal(t, "https://example.com/b", url.Target(Visitor{Variant: "B"}))
+	assert.Equal(t, "https://example.de", url.Target(Visitor{Country: "DE", Variant: "B"}))
+	// platform rules come first
+	assert.Equal(t, "https://apps.apple.com/app/example", url.Target(Visitor{UserAgent: iPhone, Country: "DE"}))
+}
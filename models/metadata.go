@@ -0,0 +1,37 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guregu/null"
+)
+
+// Metadata describes the page a url points to, as fetched by the metadata
+// job. Fetched is null until it ran.
+type Metadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	Fetched     null.Time `json:"fetched"`
+}
+
+// Value marshals the metadata to JSONB
+func (m Metadata) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan unmarshals the metadata from JSONB
+func (m *Metadata) Scan(src interface{}) error {
+	if src == nil {
+		*m = Metadata{}
+		return nil
+	}
+	source, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("Type assertion .([]byte) failed.")
+	}
+	return json.Unmarshal(source, m)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"net/http"
+)
+
+// MaxCacheControlLength is how long the Cache-Control header of a url may be
+const MaxCacheControlLength = 256
+
+// redirectCodes are the status codes a url may redirect with
+var redirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// referrerPolicies are the values of the Referrer-Policy header
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+// IsValidRedirectCode tells whether a url may redirect with code, 0 being
+// the default
+func IsValidRedirectCode(code int) bool {
+	return code == 0 || redirectCodes[code]
+}
+
+// IsValidReferrerPolicy tells whether policy is a Referrer-Policy, empty
+// leaving the header out
+func IsValidReferrerPolicy(policy string) bool {
+	return policy == "" || referrerPolicies[policy]
+}
+
+// IsValidCacheControl tells whether value can be sent as the Cache-Control
+// header. Only printable ASCII is allowed so a value cannot add headers.
+func IsValidCacheControl(value string) bool {
+	if len(value) > MaxCacheControlLength {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < ' ' || value[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RedirectStatus is the status code the url redirects with
+func (u *URL) RedirectStatus() int {
+	if u.RedirectCode == 0 {
+		return http.StatusFound
+	}
+	return u.RedirectCode
+}
+
+// RedirectHeaders are the headers the owner of the url set on its redirect
+func (u *URL) RedirectHeaders() http.Header {
+	header := http.Header{}
+	if u.CacheControl != "" {
+		header.Set("Cache-Control", u.CacheControl)
+	}
+	if u.NoIndex {
+		header.Set("X-Robots-Tag", "noindex")
+	}
+	if u.ReferrerPolicy != "" {
+		header.Set("Referrer-Policy", u.ReferrerPolicy)
+	}
+	return header
+}
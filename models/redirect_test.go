@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectStatus(t *testing.T) {
+	url := &URL{}
+	assert.Equal(t, 302, url.RedirectStatus())
+
+	url.RedirectCode = 308
+	assert.Equal(t, 308, url.RedirectStatus())
+
+	assert.True(t, IsValidRedirectCode(0))
+	assert.True(t, IsValidRedirectCode(301))
+	assert.True(t, IsValidRedirectCode(307))
+	assert.False(t, IsValidRedirectCode(200))
+	assert.False(t, IsValidRedirectCode(303))
+}
+
+func TestRedirectHeaders(t *testing.T) {
+	url := &URL{}
+	assert.Empty(t, url.RedirectHeaders())
+
+	url.CacheControl = "public, max-age=3600"
+	url.NoIndex = true
+	url.ReferrerPolicy = "no-referrer"
+	header := url.RedirectHeaders()
+	assert.Equal(t, "public, max-age=3600", header.Get("Cache-Control"))
+	assert.Equal(t, "noindex", header.Get("X-Robots-Tag"))
+	assert.Equal(t, "no-referrer", header.Get("Referrer-Policy"))
+
+	assert.True(t, IsValidReferrerPolicy(""))
+	assert.True(t, IsValidReferrerPolicy("strict-origin-when-cross-origin"))
+	assert.False(t, IsValidReferrerPolicy("everything"))
+
+	assert.True(t, IsValidCacheControl("no-store"))
+	assert.False(t, IsValidCacheControl("no-store\r\nSet-Cookie: a=b"))
+	assert.False(t, IsValidCacheControl(strings.Repeat("a", MaxCacheControlLength+1)))
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ReservedRoute     = "route"
+	ReservedProfanity = "profanity"
+	ReservedBrand     = "brand"
+)
+
+// ReservedSlug is a slug that cannot be used as an alias
+type ReservedSlug struct {
+	Slug     string    `json:"slug" db:"slug"`
+	Reason   string    `json:"reason" db:"reason"`
+	Username string    `json:"username" db:"username"`
+	Created  time.Time `json:"created" db:"created"`
+}
+
+func IsValidReservedReason(reason string) bool {
+	switch reason {
+	case ReservedRoute, ReservedProfanity, ReservedBrand:
+		return true
+	}
+	return false
+}
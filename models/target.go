@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	// PlatformMobile and PlatformDesktop match any platform of their kind
+	PlatformMobile  = "mobile"
+	PlatformDesktop = "desktop"
+
+	// MaxTargetRules is how many targeting rules a url may have
+	MaxTargetRules = 10
+)
+
+// Visitor is who requested a short link, as far as targeting rules care
+type Visitor struct {
+	UserAgent string
+	// Country and Region are ISO codes, empty when the location is unknown
+	Country string
+	Region  string
+	// Variant is the A/B variant the visitor was assigned to
+	Variant string
+}
+
+// TargetRule sends the visitors on a platform to another destination
+type TargetRule struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// TargetRules are evaluated in order, the first matching rule wins
+type TargetRules []TargetRule
+
+func Platforms() []string {
+	return []string{PlatformIOS, PlatformAndroid, PlatformMobile, PlatformWindows, PlatformMacOS, PlatformLinux, PlatformDesktop}
+}
+
+func IsValidPlatform(platform string) bool {
+	for _, p := range Platforms() {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectPlatform returns the platform of a User-Agent, or an empty string if
+// it is unknown
+func DetectPlatform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Windows Phone"):
+		return ""
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		return PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return PlatformMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"), strings.Contains(userAgent, "CrOS"):
+		return PlatformLinux
+	}
+	return ""
+}
+
+// Matches tells whether the rule applies to a User-Agent
+func (r TargetRule) Matches(userAgent string) bool {
+	platform := DetectPlatform(userAgent)
+	switch r.Platform {
+	case PlatformMobile:
+		return platform == PlatformIOS || platform == PlatformAndroid || strings.Contains(userAgent, "Mobile")
+	case PlatformDesktop:
+		return platform == PlatformWindows || platform == PlatformMacOS || platform == PlatformLinux
+	}
+	return platform != "" && r.Platform == platform
+}
+
+// Target returns the destination of the first platform rule matching a
+// visitor, then of the first matching geo rule, then of the variant of the
+// visitor, or the url of the link when none does
+func (u *URL) Target(visitor Visitor) string {
+	for _, rule := range u.Targets {
+		if rule.Matches(visitor.UserAgent) {
+			return rule.URL
+		}
+	}
+	for _, rule := range u.GeoTargets {
+		if rule.Matches(visitor.Country, visitor.Region) {
+			return rule.URL
+		}
+	}
+	if variant := u.Variants.Find(visitor.Variant); variant != nil {
+		return variant.URL
+	}
+	return u.Url
+}
+
+// String lists the rules in order, e.g. "ios: https://apps.apple.com/app"
+func (t TargetRules) String() string {
+	rules := make([]string, 0, len(t))
+	for _, rule := range t {
+		rules = append(rules, fmt.Sprintf("%v: %v", rule.Platform, rule.URL))
+	}
+	return strings.Join(rules, ", ")
+}
+
+// Value marshals the rules to JSONB
+func (t TargetRules) Value() (driver.Value, error) {
+	if t == nil {
+		t = TargetRules{}
+	}
+	return json.Marshal(t)
+}
+
+// Scan unmarshals the rules from JSONB
+func (t *TargetRules) Scan(src interface{}) error {
+	if src == nil {
+		*t = nil
+		return nil
+	}
+	source, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("Type assertion .([]byte) failed.")
+	}
+	return json.Unmarshal(source, t)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	assert.Equal(t, PlatformIOS, DetectPlatform("Mozilla/5.0 (iPad; CPU OS 12_0 like Mac OS X) AppleWebKit/605.1.15"))
+	assert.Equal(t, PlatformAndroid, DetectPlatform("Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36"))
+	assert.Equal(t, PlatformWindows, DetectPlatform("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"))
+	assert.Equal(t, PlatformMacOS, DetectPlatform("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_0) AppleWebKit/605.1.15"))
+	assert.Equal(t, PlatformLinux, DetectPlatform("Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:63.0) Gecko/20100101 Firefox/63.0"))
+	assert.Equal(t, "", DetectPlatform("curl/7.61.0"))
+}
+
+func TestTargetRuleMatches(t *testing.T) {
+	windows := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+	android := "Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36 Mobile Safari/537.36"
+
+	assert.True(t, TargetRule{Platform: PlatformDesktop}.Matches(windows))
+	assert.False(t, TargetRule{Platform: PlatformMobile}.Matches(windows))
+	assert.True(t, TargetRule{Platform: PlatformMobile}.Matches(android))
+	assert.False(t, TargetRule{Platform: PlatformLinux}.Matches(android))
+	assert.False(t, TargetRule{Platform: PlatformIOS}.Matches(""))
+}
+
+func TestTargetRulesScan(t *testing.T) {
+	rules := TargetRules{{Platform: PlatformIOS, URL: "https://apps.apple.com/app/example"}}
+	value, err := rules.Value()
+	assert.Nil(t, err)
+
+	var scanned TargetRules
+	assert.Nil(t, scanned.Scan(value))
+	assert.Equal(t, rules, scanned)
+	assert.Equal(t, "ios: https://apps.apple.com/app/example", scanned.String())
+
+	value, err = TargetRules(nil).Value()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("[]"), value)
+}
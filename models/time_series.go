@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+	// IntervalWeek buckets start on Monday
+	IntervalWeek = "week"
+
+	// MaxTimeBuckets is how many buckets a time series may have
+	MaxTimeBuckets = 1000
+)
+
+// TimeBucket counts the clicks from Start until the next bucket
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// TimeSeries counts the clicks of a url in [From, To) and in the period of
+// as many buckets right before it
+type TimeSeries struct {
+	Interval      string       `json:"interval"`
+	Timezone      string       `json:"timezone"`
+	From          time.Time    `json:"from"`
+	To            time.Time    `json:"to"`
+	Total         int          `json:"total"`
+	Buckets       []TimeBucket `json:"buckets"`
+	PreviousFrom  time.Time    `json:"previous_from"`
+	PreviousTotal int          `json:"previous_total"`
+	Previous      []TimeBucket `json:"previous"`
+}
+
+func IsValidInterval(interval string) bool {
+	return interval == IntervalHour || interval == IntervalDay || interval == IntervalWeek
+}
+
+// HasWholeHourOffset tells whether loc is a whole number of hours from UTC at
+// t. Buckets are added up from hourly stats, so zones like Asia/Kolkata
+// cannot be bucketed.
+func HasWholeHourOffset(loc *time.Location, t time.Time) bool {
+	_, offset := t.In(loc).Zone()
+	return offset%3600 == 0
+}
+
+// BucketStart returns the start of the bucket of t in loc
+func BucketStart(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		monday := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		return time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// AddBuckets moves the start of a bucket n buckets later, or earlier when n
+// is negative. Days and weeks follow the calendar across DST changes.
+func AddBuckets(start time.Time, interval string, n int) time.Time {
+	switch interval {
+	case IntervalHour:
+		return BucketStart(start.Add(time.Duration(n)*time.Hour), interval, start.Location())
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7*n)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// CountBuckets returns the number of buckets between the bucket of from and
+// to
+func CountBuckets(from, to time.Time, interval string, loc *time.Location) int {
+	var n int
+	for start := BucketStart(from, interval, loc); start.Before(to) && n <= MaxTimeBuckets; start = AddBuckets(start, interval, 1) {
+		n++
+	}
+	return n
+}
+
+// FillBuckets adds up hourly counts into buckets from the bucket of from
+// until to. Buckets without clicks are kept with a count of 0.
+func FillBuckets(stats []URLHourlyStat, from, to time.Time, interval string, loc *time.Location) ([]TimeBucket, int) {
+	counts := make(map[int64]int)
+	for _, stat := range stats {
+		counts[BucketStart(stat.Hour, interval, loc).Unix()] += stat.Counter
+	}
+
+	buckets := make([]TimeBucket, 0)
+	var total int
+	for start := BucketStart(from, interval, loc); start.Before(to) && len(buckets) < MaxTimeBuckets; start = AddBuckets(start, interval, 1) {
+		count := counts[start.Unix()]
+		buckets = append(buckets, TimeBucket{Start: start, Count: count})
+		total += count
+	}
+	return buckets, total
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketStart(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.Nil(t, err)
+
+	// a Thursday evening in UTC is already Friday in Berlin
+	clicked := time.Date(2018, 11, 1, 23, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2018, 11, 2, 0, 0, 0, 0, berlin), BucketStart(clicked, IntervalHour, berlin))
+	assert.Equal(t, time.Date(2018, 11, 2, 0, 0, 0, 0, berlin), BucketStart(clicked, IntervalDay, berlin))
+	assert.Equal(t, time.Date(2018, 10, 29, 0, 0, 0, 0, berlin), BucketStart(clicked, IntervalWeek, berlin))
+	assert.Equal(t, time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC), BucketStart(clicked, IntervalDay, time.UTC))
+
+	// days keep starting at midnight across DST changes
+	start := time.Date(2018, 10, 28, 0, 0, 0, 0, berlin)
+	assert.Equal(t, time.Date(2018, 10, 29, 0, 0, 0, 0, berlin), AddBuckets(start, IntervalDay, 1))
+	assert.Equal(t, time.Date(2018, 10, 21, 0, 0, 0, 0, berlin), AddBuckets(start, IntervalWeek, -1))
+	assert.Equal(t, start.Add(-3*time.Hour), AddBuckets(start, IntervalHour, -3))
+}
+
+func TestHasWholeHourOffset(t *testing.T) {
+	now := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+	for tz, expected := range map[string]bool{
+		"UTC":                true,
+		"Europe/Berlin":      true,
+		"America/New_York":   true,
+		"Asia/Kolkata":       false,
+		"Australia/Adelaide": false,
+		"America/St_Johns":   false,
+	} {
+		loc, err := time.LoadLocation(tz)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, HasWholeHourOffset(loc, now), tz)
+	}
+}
+
+func TestFillBuckets(t *testing.T) {
+	from := time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 3)
+	stats := []URLHourlyStat{
+		{Hour: from.Add(2 * time.Hour), Counter: 3},
+		{Hour: from.Add(20 * time.Hour), Counter: 1},
+		{Hour: from.Add(50 * time.Hour), Counter: 4},
+	}
+
+	buckets, total := FillBuckets(stats, from, to, IntervalDay, time.UTC)
+	assert.Equal(t, 8, total)
+	assert.Equal(t, []TimeBucket{
+		{Start: from, Count: 4},
+		{Start: from.AddDate(0, 0, 1), Count: 0},
+		{Start: from.AddDate(0, 0, 2), Count: 4},
+	}, buckets)
+	assert.Equal(t, 3, CountBuckets(from, to, IntervalDay, time.UTC))
+
+	buckets, total = FillBuckets(stats, from, from.Add(6*time.Hour), IntervalHour, time.UTC)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 6, len(buckets))
+	assert.Equal(t, 3, buckets[2].Count)
+
+	assert.True(t, IsValidInterval(IntervalWeek))
+	assert.False(t, IsValidInterval("month"))
+}
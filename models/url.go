@@ -3,6 +3,9 @@ package models
 import (
 	"fmt"
 	"math/rand"
+	url2 "net/url"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/guregu/null"
@@ -14,10 +17,20 @@ const (
 	Active  = "active"
 	Pending = "pending"
 	Expired = "expired"
+
+	// ForwardQueryKeep merges the query of the short link into the
+	// destination, keeping the destination values of parameters in both.
+	// ForwardQueryOverride lets the short link values win instead.
+	ForwardQueryKeep     = "keep"
+	ForwardQueryOverride = "override"
+
+	// MaxDescriptionLength is how long the description of a url may be
+	MaxDescriptionLength = 500
 )
 
 type URL struct {
 	Url      string    `json:"url" db:"url"`
+	Domain   string    `json:"domain" db:"domain"`
 	Slug     string    `json:"slug" db:"slug"`
 	IP       string    `json:"ip" db:"ip"`
 	Counter  int       `json:"counter" db:"counter"`
@@ -28,6 +41,93 @@ type URL struct {
 	Expired  null.Time `json:"expired" db:"expired"`
 	Mindful  bool      `json:"mindful" db:"mindful"`
 	Username string    `json:"username" db:"username"`
+	Deleted  null.Time `json:"deleted" db:"deleted"`
+
+	ForwardQuery string      `json:"forward_query" db:"forward_query"`
+	ForwardPath  bool        `json:"forward_path" db:"forward_path"`
+	Targets      TargetRules `json:"targets" db:"targets"`
+	GeoTargets   GeoRules    `json:"geo_targets" db:"geo_targets"`
+	Variants     Variants    `json:"variants" db:"variants"`
+	// MaxClicks is how many clicks the url redirects before it expires,
+	// 0 for no limit
+	MaxClicks int `json:"max_clicks" db:"max_clicks"`
+	// NotBefore is when the url starts redirecting
+	NotBefore null.Time `json:"not_before" db:"not_before"`
+
+	// RedirectCode is the status code of the redirect, 0 for 302
+	RedirectCode   int    `json:"redirect_code" db:"redirect_code"`
+	CacheControl   string `json:"cache_control" db:"cache_control"`
+	NoIndex        bool   `json:"no_index" db:"no_index"`
+	ReferrerPolicy string `json:"referrer_policy" db:"referrer_policy"`
+
+	// Description is shown to visitors previewing the url
+	Description string `json:"description" db:"description"`
+	// Metadata is what the metadata job found on the destination page
+	Metadata Metadata `json:"metadata" db:"metadata"`
+	// Card is shown to chat apps unfurling the short link instead of the
+	// metadata of the destination
+	Card Card `json:"card" db:"card"`
+}
+
+// IsRestorable tells whether a url in the trash can still be restored
+func (u *URL) IsRestorable(retention time.Duration) bool {
+	return u.Deleted.Valid && u.Deleted.Time.Add(retention).After(time.Now())
+}
+
+// IsExhausted tells whether a click-limited url used up its clicks
+func (u *URL) IsExhausted() bool {
+	return u.MaxClicks > 0 && u.Counter >= u.MaxClicks
+}
+
+// IsSafe tells whether the spam job found no threats on the url
+func (u *URL) IsSafe() bool {
+	return u.Status == Active || u.Status == Pending || u.Status == Expired
+}
+
+// IsLaunched tells whether a url with a not-before time already redirects
+func (u *URL) IsLaunched(now time.Time) bool {
+	return !u.NotBefore.Valid || !u.NotBefore.Time.After(now)
+}
+
+func IsValidForwardQuery(mode string) bool {
+	return mode == "" || mode == ForwardQueryKeep || mode == ForwardQueryOverride
+}
+
+// Destination returns the url to redirect to from a request of the short link
+// by a visitor with the extra path after the slug and the query, depending on
+// the targeting rules and forwarding options of the url
+func (u *URL) Destination(visitor Visitor, extraPath string, query url2.Values) string {
+	target := u.Target(visitor)
+	trailingSlash := strings.HasSuffix(extraPath, "/")
+	extraPath = strings.TrimPrefix(path.Clean("/"+extraPath), "/")
+	if trailingSlash && extraPath != "" {
+		extraPath += "/"
+	}
+	forwardPath := u.ForwardPath && extraPath != ""
+	forwardQuery := u.ForwardQuery != "" && len(query) > 0
+	if !forwardPath && !forwardQuery {
+		return target
+	}
+
+	destination, err := url2.Parse(target)
+	if err != nil {
+		return target
+	}
+	if forwardPath {
+		destination.Path = strings.TrimSuffix(destination.Path, "/") + "/" + extraPath
+		destination.RawPath = ""
+	}
+	if forwardQuery {
+		values := destination.Query()
+		for key, value := range query {
+			if _, ok := values[key]; ok && u.ForwardQuery == ForwardQueryKeep {
+				continue
+			}
+			values[key] = value
+		}
+		destination.RawQuery = values.Encode()
+	}
+	return destination.String()
 }
 
 func TransformPassword(val string) (string, error) {
@@ -42,14 +142,29 @@ func VerifyPassword(hashedPassword string, val string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(val))
 }
 
-func GenerateSlug(size int) string {
-	s := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(s)
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
 
+// GenerateSlug returns a random slug of the given size. It relies on the
+// shared math/rand source so concurrent calls do not repeat each other.
+func GenerateSlug(size int) string {
 	var slug string
 	for i := 0; i < size; i++ {
-		idx := r.Intn(len(base))
+		idx := rand.Intn(len(base))
 		slug = slug + string(base[idx])
 	}
 	return slug
 }
+
+// EncodeSlug encodes n with the slug alphabet
+func EncodeSlug(n uint64) string {
+	if n == 0 {
+		return string(base[0])
+	}
+	var slug []byte
+	for ; n > 0; n /= uint64(len(base)) {
+		slug = append([]byte{base[n%uint64(len(base))]}, slug...)
+	}
+	return string(slug)
+}
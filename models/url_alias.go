@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// URLAlias is a former slug of a url that keeps redirecting to it
+type URLAlias struct {
+	Domain  string    `json:"domain" db:"domain"`
+	Alias   string    `json:"alias" db:"alias"`
+	Slug    string    `json:"slug" db:"slug"`
+	Created time.Time `json:"created" db:"created"`
+}
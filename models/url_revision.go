@@ -0,0 +1,79 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionStatus  = "status"
+	RevisionRestore = "restore"
+
+	SourceWeb    = "web"
+	SourceAPI    = "api"
+	SourceWorker = "worker"
+)
+
+// URLRevision is an entry of the audit trail of a url
+type URLRevision struct {
+	ID        int64       `json:"id" db:"id"`
+	Domain    string      `json:"domain" db:"domain"`
+	Slug      string      `json:"slug" db:"slug"`
+	Action    string      `json:"action" db:"action"`
+	Username  string      `json:"username" db:"username"`
+	Source    string      `json:"source" db:"source"`
+	OldValues PropertyMap `json:"old_values" db:"old_values"`
+	NewValues PropertyMap `json:"new_values" db:"new_values"`
+	Created   time.Time   `json:"created" db:"created"`
+}
+
+// RevisionValues returns the fields of a url tracked by its revisions.
+// Password hashes are never recorded, only whether there is one.
+func (u *URL) RevisionValues() PropertyMap {
+	values := PropertyMap{
+		"url":                u.Url,
+		"slug":               u.Slug,
+		"status":             u.Status,
+		"password_protected": u.Password != "",
+		"mindful":            u.Mindful,
+		"expired":            nil,
+		"forward_query":      u.ForwardQuery,
+		"forward_path":       u.ForwardPath,
+		"targets":            u.Targets.String(),
+		"geo_targets":        u.GeoTargets.String(),
+		"variants":           u.Variants.String(),
+		"max_clicks":         u.MaxClicks,
+		"not_before":         nil,
+		"redirect_code":      u.RedirectCode,
+		"cache_control":      u.CacheControl,
+		"no_index":           u.NoIndex,
+		"referrer_policy":    u.ReferrerPolicy,
+		"description":        u.Description,
+		"card_title":         u.Card.Title,
+		"card_description":   u.Card.Description,
+		"card_image":         u.Card.Image,
+	}
+	if u.Expired.Valid {
+		values["expired"] = u.Expired.Time.UTC().Format(time.RFC3339)
+	}
+	if u.NotBefore.Valid {
+		values["not_before"] = u.NotBefore.Time.UTC().Format(time.RFC3339)
+	}
+	return values
+}
+
+// DiffRevisionValues returns the values of old and new that differ
+func DiffRevisionValues(old, new PropertyMap) (PropertyMap, PropertyMap) {
+	oldDiff := PropertyMap{}
+	newDiff := PropertyMap{}
+	for key, value := range new {
+		if !reflect.DeepEqual(old[key], value) {
+			oldDiff[key] = old[key]
+			newDiff[key] = value
+		}
+	}
+	return oldDiff, newDiff
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRevisionValues(t *testing.T) {
+	url := &URL{
+		Url:      "https://example.com",
+		Slug:     "abc",
+		Status:   Active,
+		Password: "hash",
+	}
+	old := url.RevisionValues()
+	assert.Equal(t, true, old["password_protected"])
+	assert.Nil(t, old["expired"])
+
+	url.Url = "https://example.org"
+	url.Expired = null.TimeFrom(time.Date(2018, 10, 31, 13, 57, 0, 0, time.UTC))
+
+	oldDiff, newDiff := DiffRevisionValues(old, url.RevisionValues())
+	assert.Equal(t, PropertyMap{"url": "https://example.com", "expired": nil}, oldDiff)
+	assert.Equal(t, PropertyMap{"url": "https://example.org", "expired": "2018-10-31T13:57:00Z"}, newDiff)
+
+	oldDiff, newDiff = DiffRevisionValues(old, old)
+	assert.Equal(t, 0, len(oldDiff))
+	assert.Equal(t, 0, len(newDiff))
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+const RevisionSchedule = "schedule"
+
+// URLSchedule is a future change of the destination of a url
+type URLSchedule struct {
+	ID        int64     `json:"id" db:"id"`
+	Domain    string    `json:"domain" db:"domain"`
+	Slug      string    `json:"slug" db:"slug"`
+	URL       string    `json:"url" db:"url"`
+	Scheduled time.Time `json:"scheduled" db:"scheduled"`
+	Applied   null.Time `json:"applied" db:"applied"`
+	Username  string    `json:"username" db:"username"`
+	Created   time.Time `json:"created" db:"created"`
+}
@@ -7,6 +7,7 @@ import (
 )
 
 type URLStat struct {
+	Domain     string      `json:"domain" db:"domain"`
 	Slug       string      `json:"slug" db:"slug"`
 	Country    string      `json:"country" db:"country"`
 	State      string      `json:"state" db:"state"`
@@ -15,3 +16,76 @@ type URLStat struct {
 	Created    time.Time   `json:"created" db:"created"`
 	Updated    null.Time   `json:"updated" db:"updated"`
 }
+
+// ClickSourceQR marks clicks that came from scanning the QR code of a url
+const ClickSourceQR = "qr"
+
+// IsValidClickSource tells whether source is a click source we count
+func IsValidClickSource(source string) bool {
+	return source == ClickSourceQR
+}
+
+// URLSourceStat counts the clicks of a url that came from a source and
+// medium, e.g. twitter / social
+type URLSourceStat struct {
+	Domain  string    `json:"domain" db:"domain"`
+	Slug    string    `json:"slug" db:"slug"`
+	Source  string    `json:"source" db:"source"`
+	Medium  string    `json:"medium" db:"medium"`
+	Counter int       `json:"counter" db:"counter"`
+	Created time.Time `json:"created" db:"created"`
+	Updated null.Time `json:"updated" db:"updated"`
+}
+
+// URLCampaignStat counts the clicks of a url tagged with a utm_campaign
+type URLCampaignStat struct {
+	Domain   string    `json:"domain" db:"domain"`
+	Slug     string    `json:"slug" db:"slug"`
+	Campaign string    `json:"campaign" db:"campaign"`
+	Counter  int       `json:"counter" db:"counter"`
+	Created  time.Time `json:"created" db:"created"`
+	Updated  null.Time `json:"updated" db:"updated"`
+}
+
+// URLDeviceStat counts the clicks of a url from a browser, OS and device
+// class, e.g. Chrome / Android / Mobile
+type URLDeviceStat struct {
+	Domain  string    `json:"domain" db:"domain"`
+	Slug    string    `json:"slug" db:"slug"`
+	Browser string    `json:"browser" db:"browser"`
+	OS      string    `json:"os" db:"os"`
+	Device  string    `json:"device" db:"device"`
+	Counter int       `json:"counter" db:"counter"`
+	Created time.Time `json:"created" db:"created"`
+	Updated null.Time `json:"updated" db:"updated"`
+}
+
+// URLBotStat counts the hits of a url by bots, by the reason they were told
+// apart from humans
+type URLBotStat struct {
+	Domain  string    `json:"domain" db:"domain"`
+	Slug    string    `json:"slug" db:"slug"`
+	Reason  string    `json:"reason" db:"reason"`
+	Counter int       `json:"counter" db:"counter"`
+	Created time.Time `json:"created" db:"created"`
+	Updated null.Time `json:"updated" db:"updated"`
+}
+
+// URLVariantStat counts the clicks of a url that went to one of its variants
+type URLVariantStat struct {
+	Domain  string    `json:"domain" db:"domain"`
+	Slug    string    `json:"slug" db:"slug"`
+	Variant string    `json:"variant" db:"variant"`
+	Counter int       `json:"counter" db:"counter"`
+	Created time.Time `json:"created" db:"created"`
+	Updated null.Time `json:"updated" db:"updated"`
+}
+
+// URLHourlyStat counts the clicks of a url in the hour starting at Hour, in
+// UTC. Time series are added up from them instead of the click events.
+type URLHourlyStat struct {
+	Domain  string    `json:"domain" db:"domain"`
+	Slug    string    `json:"slug" db:"slug"`
+	Hour    time.Time `json:"hour" db:"hour"`
+	Counter int       `json:"counter" db:"counter"`
+}
@@ -1,8 +1,11 @@
 package models
 
 import (
+	url2 "net/url"
 	"testing"
+	"time"
 
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,3 +16,95 @@ func TestPassword(t *testing.T) {
 	err = VerifyPassword(hashPassword, "abcdef")
 	assert.Nil(t, err)
 }
+
+func TestIsRestorable(t *testing.T) {
+	url := &URL{}
+	assert.False(t, url.IsRestorable(time.Hour))
+
+	url.Deleted = null.TimeFrom(time.Now().Add(-time.Minute))
+	assert.True(t, url.IsRestorable(time.Hour))
+
+	url.Deleted = null.TimeFrom(time.Now().Add(-2 * time.Hour))
+	assert.False(t, url.IsRestorable(time.Hour))
+}
+
+func TestIsExhausted(t *testing.T) {
+	url := &URL{Counter: 5}
+	assert.False(t, url.IsExhausted())
+
+	url.MaxClicks = 6
+	assert.False(t, url.IsExhausted())
+
+	url.MaxClicks = 5
+	assert.True(t, url.IsExhausted())
+}
+
+func TestIsLaunched(t *testing.T) {
+	now := time.Now()
+	url := &URL{}
+	assert.True(t, url.IsLaunched(now))
+
+	url.NotBefore = null.TimeFrom(now.Add(time.Hour))
+	assert.False(t, url.IsLaunched(now))
+	assert.True(t, url.IsLaunched(now.Add(time.Hour)))
+}
+
+func TestEncodeSlug(t *testing.T) {
+	assert.Equal(t, "1", EncodeSlug(0))
+	assert.Equal(t, "2", EncodeSlug(1))
+	assert.Equal(t, "Z", EncodeSlug(57))
+	assert.Equal(t, "21", EncodeSlug(58))
+	assert.Equal(t, "2111", EncodeSlug(58*58*58))
+}
+
+func TestGenerateSlug(t *testing.T) {
+	slugs := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		slug := GenerateSlug(6)
+		assert.Equal(t, 6, len(slug))
+		assert.False(t, slugs[slug])
+		slugs[slug] = true
+	}
+}
+
+func TestDestination(t *testing.T) {
+	url := &URL{Url: "https://example.com/base?utm_source=site&a=1"}
+	query := url2.Values{"utm_source": {"mail"}, "ref": {"abc"}}
+
+	// nothing is forwarded by default
+	assert.Equal(t, url.Url, url.Destination(Visitor{}, "/docs", query))
+
+	url.ForwardQuery = ForwardQueryKeep
+	assert.Equal(t, "https://example.com/base?a=1&ref=abc&utm_source=site", url.Destination(Visitor{}, "", query))
+	assert.Equal(t, url.Url, url.Destination(Visitor{}, "", nil))
+
+	url.ForwardQuery = ForwardQueryOverride
+	assert.Equal(t, "https://example.com/base?a=1&ref=abc&utm_source=mail", url.Destination(Visitor{}, "", query))
+
+	url.ForwardQuery = ""
+	url.ForwardPath = true
+	assert.Equal(t, "https://example.com/base/docs/getting-started?utm_source=site&a=1", url.Destination(Visitor{}, "/docs/getting-started", nil))
+	assert.Equal(t, "https://example.com/base/docs/?utm_source=site&a=1", url.Destination(Visitor{}, "/docs/", nil))
+	assert.Equal(t, "https://example.com/base/etc?utm_source=site&a=1", url.Destination(Visitor{}, "/../../etc", nil))
+	assert.Equal(t, url.Url, url.Destination(Visitor{}, "/", nil))
+}
+
+func TestDestinationTargets(t *testing.T) {
+	url := &URL{
+		Url: "https://example.com",
+		Targets: TargetRules{
+			{Platform: PlatformIOS, URL: "https://apps.apple.com/app/example"},
+			{Platform: PlatformMobile, URL: "https://play.google.com/store/apps/details?id=com.example"},
+		},
+		ForwardPath: true,
+	}
+	iPhone := "Mozilla/5.0 (iPhone; CPU iPhone OS 12_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	android := "Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36 Chrome/70.0 Mobile Safari/537.36"
+	mac := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_0) AppleWebKit/605.1.15 Safari/605.1.15"
+
+	assert.Equal(t, "https://apps.apple.com/app/example", url.Destination(Visitor{UserAgent: iPhone}, "", nil))
+	assert.Equal(t, "https://play.google.com/store/apps/details?id=com.example", url.Destination(Visitor{UserAgent: android}, "", nil))
+	assert.Equal(t, "https://example.com", url.Destination(Visitor{UserAgent: mac}, "", nil))
+	assert.Equal(t, "https://example.com", url.Destination(Visitor{}, "", nil))
+	assert.Equal(t, "https://apps.apple.com/app/example/docs", url.Destination(Visitor{UserAgent: iPhone}, "/docs", nil))
+}
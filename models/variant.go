@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxVariants is how many destinations an A/B link may rotate between
+	MaxVariants = 10
+	// MaxVariantWeight keeps the total weight of a link from overflowing
+	MaxVariantWeight = 1000
+)
+
+var variantNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// Variant is one of the destinations of an A/B link, chosen with a
+// probability of its weight over the total weight
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+type Variants []Variant
+
+func (v Variant) IsValid() bool {
+	return variantNameRegexp.MatchString(v.Name) && v.Weight > 0 && v.Weight <= MaxVariantWeight
+}
+
+// Choose returns a variant at random by weight
+func (v Variants) Choose() *Variant {
+	var total int
+	for _, variant := range v {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	n := rand.Intn(total)
+	for i := range v {
+		if n < v[i].Weight {
+			return &v[i]
+		}
+		n -= v[i].Weight
+	}
+	return nil
+}
+
+// Find returns the variant with a name, or nil
+func (v Variants) Find(name string) *Variant {
+	for i := range v {
+		if v[i].Name == name {
+			return &v[i]
+		}
+	}
+	return nil
+}
+
+// String lists the variants in order, e.g. "A (70): https://example.com/a"
+func (v Variants) String() string {
+	variants := make([]string, 0, len(v))
+	for _, variant := range v {
+		variants = append(variants, fmt.Sprintf("%v (%v): %v", variant.Name, variant.Weight, variant.URL))
+	}
+	return strings.Join(variants, ", ")
+}
+
+// Value marshals the variants to JSONB
+func (v Variants) Value() (driver.Value, error) {
+	if v == nil {
+		v = Variants{}
+	}
+	return json.Marshal(v)
+}
+
+// Scan unmarshals the variants from JSONB
+func (v *Variants) Scan(src interface{}) error {
+	if src == nil {
+		*v = nil
+		return nil
+	}
+	source, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("Type assertion .([]byte) failed.")
+	}
+	return json.Unmarshal(source, v)
+}
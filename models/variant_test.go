@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariantsChoose(t *testing.T) {
+	variants := Variants{
+		{Name: "A", URL: "https://example.com/a", Weight: 70},
+		{Name: "B", URL: "https://example.com/b", Weight: 30},
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[variants.Choose().Name]++
+	}
+	assert.InDelta(t, 7000, counts["A"], 500)
+	assert.InDelta(t, 3000, counts["B"], 500)
+
+	assert.Equal(t, "B", Variants{{Name: "A"}, {Name: "B", Weight: 1}}.Choose().Name)
+	assert.Nil(t, Variants{}.Choose())
+}
+
+func TestVariants(t *testing.T) {
+	variants := Variants{{Name: "A", URL: "https://example.com/a", Weight: 1}}
+	assert.Equal(t, "https://example.com/a", variants.Find("A").URL)
+	assert.Nil(t, variants.Find("C"))
+	assert.Equal(t, "A (1): https://example.com/a", variants.String())
+
+	assert.True(t, variants[0].IsValid())
+	assert.False(t, Variant{Name: "A", Weight: 0}.IsValid())
+	assert.True(t, Variant{Name: "A", Weight: MaxVariantWeight}.IsValid())
+	assert.False(t, Variant{Name: "A", Weight: MaxVariantWeight + 1}.IsValid())
+	assert.False(t, Variant{Name: "a b", Weight: 1}.IsValid())
+
+	value, err := variants.Value()
+	assert.Nil(t, err)
+	var scanned Variants
+	assert.Nil(t, scanned.Scan(value))
+	assert.Equal(t, variants, scanned)
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/pg"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AuthorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+	apiKeyUser          = "APIKeyUser"
+	sessionIgnored      = "SessionIgnored"
+)
+
+var (
+	ErrInvalidAPIKey     = errors.New("Invalid API key")
+	ErrInsufficientScope = errors.New("API key is missing the required scope")
+)
+
+// AuthenticateAPIKey verifies the bearer API key of the request has the given
+// scope and makes the key owner the authenticated user of the request.
+// Requests without an Authorization header are left untouched.
+func AuthenticateAPIKey(c *gin.Context, scope string) error {
+	header := c.GetHeader(AuthorizationHeader)
+	if header == "" {
+		return nil
+	}
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return ErrInvalidAPIKey
+	}
+
+	db := middleware.GetDB(c)
+	key, err := pg.GetAPIKeyByHash(db, models.HashAPIKey(strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvalidAPIKey
+		}
+		return err
+	}
+	if key.Revoked.Valid {
+		return ErrInvalidAPIKey
+	}
+	if !key.HasScope(scope) {
+		return ErrInsufficientScope
+	}
+
+	user, err := pg.GetUser(db, key.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvalidAPIKey
+		}
+		return err
+	}
+	if user.Status != "active" {
+		return ErrInvalidAPIKey
+	}
+
+	if err := pg.UpdateAPIKeyLastUsed(db, key); err != nil {
+		c.Error(err)
+	}
+
+	c.Set(apiKeyUser, user)
+	return nil
+}
+
+// IgnoreSession leaves a request without an authenticated user unless it has
+// an API key. Browsers send the session cookie along with requests other sites
+// make.
+func IgnoreSession(c *gin.Context) {
+	c.Set(sessionIgnored, true)
+}
+
+func HandleCreateAPIKey(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	scopes := c.PostFormArray("scopes")
+	if len(scopes) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "At least one scope is required",
+		})
+		return
+	}
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid scope: " + scope,
+			})
+			return
+		}
+	}
+
+	raw, err := models.GenerateAPIKey()
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	key := &models.APIKey{
+		Username: user.Username,
+		Name:     c.PostForm("name"),
+		Prefix:   models.APIKeyPrefix(raw),
+		Hash:     models.HashAPIKey(raw),
+		Scopes:   scopes,
+		Created:  time.Now(),
+	}
+	if err := pg.CreateAPIKey(db, key); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	log.WithField("username", user.Username).
+		WithField("prefix", key.Prefix).
+		Info("Created API key")
+
+	// the raw key is only ever returned here
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"key":     raw,
+		"data":    key,
+	})
+}
+
+func HandleGetAPIKeys(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	keys, err := pg.GetAPIKeys(db, map[string]interface{}{
+		"username": user.Username,
+		"revoked":  false,
+	})
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    keys,
+	})
+}
+
+func HandleRevokeAPIKey(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.PostForm("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid id",
+		})
+		return
+	}
+
+	// admins can revoke any key
+	username := user.Username
+	if user.Role == models.RoleAdmin {
+		username = ""
+	}
+	revoked, err := pg.RevokeAPIKey(db, id, username)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if !revoked {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	log.WithField("username", user.Username).
+		WithField("id", id).
+		Info("Revoked API key")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
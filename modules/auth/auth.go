@@ -243,6 +243,14 @@ func UpdatePassword(c *gin.Context) {
 }
 
 func GetAuthenticatedUser(c *gin.Context) *models.User {
+	// requests authenticated with an API key
+	if user, ok := c.Value(apiKeyUser).(*models.User); ok {
+		return user
+	}
+	if c.GetBool(sessionIgnored) {
+		return nil
+	}
+
 	db := middleware.GetDB(c)
 	sessionStore := middleware.GetSessionStore(c)
 	session, err := sessionStore.Get(c.Request, SessionName)
@@ -0,0 +1,109 @@
+package bots
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/useragent"
+	log "github.com/sirupsen/logrus"
+)
+
+// Reasons a request is told apart as a bot
+const (
+	ReasonHead      = "head"
+	ReasonPrefetch  = "prefetch"
+	ReasonUserAgent = "user_agent"
+	ReasonIP        = "ip"
+)
+
+// Ranges is the file of the ip ranges of known crawlers, set with
+// $CRAWLER_RANGES
+var Ranges = "static/crawlers.txt"
+
+var (
+	once     sync.Once
+	networks []*net.IPNet
+)
+
+func init() {
+	if ranges := os.Getenv("CRAWLER_RANGES"); ranges != "" {
+		Ranges = ranges
+	}
+}
+
+// Classify returns why a request for a link comes from a bot, or an empty
+// string for humans. HEAD requests and prefetches are not clicks either.
+func Classify(r *http.Request, ip string) string {
+	if r.Method == http.MethodHead {
+		return ReasonHead
+	}
+	if isPrefetch(r.Header) {
+		return ReasonPrefetch
+	}
+	userAgent := r.Header.Get("User-Agent")
+	if models.IsUnfurlBot(userAgent) || useragent.Parse(userAgent).Device == useragent.DeviceBot {
+		return ReasonUserAgent
+	}
+	if parsed := net.ParseIP(ip); parsed != nil && isCrawler(parsed) {
+		return ReasonIP
+	}
+	return ""
+}
+
+// isPrefetch tells whether a browser fetches a link ahead of a click
+func isPrefetch(header http.Header) bool {
+	for _, name := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(header.Get(name))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
+
+// isCrawler tells whether an ip is in the range of a known crawler. The
+// ranges are read on first use and shared by all requests.
+var isCrawler = func(ip net.IP) bool {
+	once.Do(func() {
+		file, err := os.Open(Ranges)
+		if err != nil {
+			log.WithField("ranges", Ranges).WithError(err).Error("error opening crawler ranges")
+			return
+		}
+		defer file.Close()
+		if networks, err = ParseRanges(file); err != nil {
+			log.WithField("ranges", Ranges).WithError(err).Error("error reading crawler ranges")
+		}
+	})
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRanges reads one CIDR per line. Blank lines and everything after a #
+// are ignored.
+func ParseRanges(r io.Reader) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if line == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(line)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, scanner.Err()
+}
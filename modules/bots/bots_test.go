@@ -0,0 +1,70 @@
+package bots
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jasontthai/tinyalias/modules/useragent"
+	"github.com/stretchr/testify/assert"
+)
+
+const browser = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36"
+
+func TestClassify(t *testing.T) {
+	useragent.Rules = "../../static/useragents.json"
+	defer func(f func(net.IP) bool) { isCrawler = f }(isCrawler)
+	isCrawler = func(ip net.IP) bool {
+		return ip.Equal(net.ParseIP("66.249.66.1"))
+	}
+
+	tests := []struct {
+		method   string
+		header   map[string]string
+		ip       string
+		expected string
+	}{
+		{"GET", map[string]string{"User-Agent": browser}, "81.169.145.1", ""},
+		{"HEAD", map[string]string{"User-Agent": browser}, "81.169.145.1", ReasonHead},
+		{"GET", map[string]string{"User-Agent": browser, "Purpose": "prefetch"}, "81.169.145.1", ReasonPrefetch},
+		{"GET", map[string]string{"User-Agent": browser, "Sec-Purpose": "prefetch;prerender"}, "81.169.145.1", ReasonPrefetch},
+		{"GET", map[string]string{"User-Agent": browser, "X-Moz": "prefetch"}, "81.169.145.1", ReasonPrefetch},
+		{"GET", map[string]string{"User-Agent": "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"}, "81.169.145.1", ReasonUserAgent},
+		{"GET", map[string]string{"User-Agent": "Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)"}, "81.169.145.1", ReasonUserAgent},
+		{"GET", map[string]string{"User-Agent": "curl/7.54.0"}, "81.169.145.1", ReasonUserAgent},
+		{"GET", map[string]string{}, "81.169.145.1", ""},
+		{"GET", map[string]string{"User-Agent": browser}, "66.249.66.1", ReasonIP},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest(test.method, "/abc", nil)
+		for key, value := range test.header {
+			r.Header.Set(key, value)
+		}
+		assert.Equal(t, test.expected, Classify(r, test.ip), "%v %v %v", test.method, test.header, test.ip)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	networks, err := ParseRanges(strings.NewReader(`# Googlebot
+66.249.64.0/19
+2001:4860:4801::/48 # ipv6
+
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(networks))
+	assert.True(t, networks[0].Contains(net.ParseIP("66.249.66.1")))
+	assert.False(t, networks[0].Contains(net.ParseIP("66.249.96.1")))
+	assert.True(t, networks[1].Contains(net.ParseIP("2001:4860:4801:10::1")))
+
+	_, err = ParseRanges(strings.NewReader("66.249.64.0"))
+	assert.NotNil(t, err)
+
+	file, err := os.Open("../../static/crawlers.txt")
+	assert.Nil(t, err)
+	defer file.Close()
+	_, err = ParseRanges(file)
+	assert.Nil(t, err)
+}
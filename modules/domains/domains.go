@@ -0,0 +1,114 @@
+package domains
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	url2 "net/url"
+	"os"
+	"strings"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/utils"
+	"github.com/jasontthai/tinyalias/pg"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errDomainNotFound    = errors.New("Domain does not exist")
+	errDomainNotVerified = errors.New("Domain is not verified yet")
+	errDomainForbidden   = errors.New("Domain belongs to another user")
+)
+
+// lookupTXT resolves the TXT records of a name
+var lookupTXT = net.LookupTXT
+
+// Verify checks that the verification record of a domain is published.
+// $SKIP_DOMAIN_VERIFICATION=true accepts every domain for local development.
+var Verify = verifyTXT
+
+var defaultHost string
+var scheme = "https"
+
+func init() {
+	if os.Getenv("SKIP_DOMAIN_VERIFICATION") == "true" {
+		Verify = func(*models.CustomDomain) error { return nil }
+	}
+
+	// BASE_URL may come without a scheme, e.g. localhost:5000/
+	baseURL := utils.BaseUrl
+	if !strings.Contains(baseURL, "://") {
+		baseURL = scheme + "://" + baseURL
+	}
+	if u, err := url2.Parse(baseURL); err == nil {
+		defaultHost = models.NormalizeHost(u.Host)
+		scheme = u.Scheme
+	}
+}
+
+func verifyTXT(domain *models.CustomDomain) error {
+	name, value := domain.VerificationRecord()
+	records, err := lookupTXT(name)
+	if err != nil {
+		return fmt.Errorf("Could not find TXT record %v", name)
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("TXT record %v does not contain %v", name, value)
+}
+
+// Resolve returns the verified custom domain a request was made to, or an
+// empty string for the default domain
+func Resolve(db *sqlx.DB, host string) (string, error) {
+	host = models.NormalizeHost(host)
+	if host == "" || host == defaultHost || strings.HasPrefix(host, "api.") {
+		return "", nil
+	}
+
+	domain, err := pg.GetCustomDomain(db, host)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	if !domain.Verified.Valid {
+		return "", nil
+	}
+	return domain.Host, nil
+}
+
+// Authorize checks that user may create links on a domain
+func Authorize(db *sqlx.DB, host string, user *models.User) (int, error) {
+	if host == "" {
+		return http.StatusOK, nil
+	}
+
+	domain, err := pg.GetCustomDomain(db, host)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return http.StatusBadRequest, errDomainNotFound
+		}
+		return http.StatusInternalServerError, err
+	}
+	if user == nil || (user.Role != models.RoleAdmin && domain.Username != user.Username) {
+		return http.StatusForbidden, errDomainForbidden
+	}
+	if !domain.Verified.Valid {
+		return http.StatusBadRequest, errDomainNotVerified
+	}
+	return http.StatusOK, nil
+}
+
+// ShortURL returns the short link of a slug on a domain
+func ShortURL(domain, slug string) string {
+	if domain == "" {
+		return utils.BaseUrl + slug
+	}
+	return fmt.Sprintf("%v://%v/%v", scheme, domain, slug)
+}
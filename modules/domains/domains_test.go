@@ -0,0 +1,33 @@
+package domains
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyTXT(t *testing.T) {
+	domain := &models.CustomDomain{Host: "go.example.com", Token: "abc"}
+
+	lookupTXT = func(name string) ([]string, error) {
+		assert.Equal(t, "_tinyalias.go.example.com", name)
+		return []string{"v=spf1 -all", "tinyalias-verification=abc"}, nil
+	}
+	assert.Nil(t, verifyTXT(domain))
+
+	lookupTXT = func(name string) ([]string, error) {
+		return []string{"tinyalias-verification=other"}, nil
+	}
+	assert.NotNil(t, verifyTXT(domain))
+
+	lookupTXT = func(name string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	assert.NotNil(t, verifyTXT(domain))
+}
+
+func TestShortURL(t *testing.T) {
+	assert.Equal(t, "https://go.example.com/abc", ShortURL("go.example.com", "abc"))
+}
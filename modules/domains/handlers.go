@@ -0,0 +1,291 @@
+package domains
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/pg"
+	log "github.com/sirupsen/logrus"
+)
+
+// Domain is a custom domain with the TXT record verifying it
+type Domain struct {
+	models.CustomDomain
+	RecordName  string `json:"record_name"`
+	RecordValue string `json:"record_value"`
+}
+
+func newDomain(domain models.CustomDomain) Domain {
+	name, value := domain.VerificationRecord()
+	return Domain{
+		CustomDomain: domain,
+		RecordName:   name,
+		RecordValue:  value,
+	}
+}
+
+func HandleGetDomains(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	clauses := map[string]interface{}{
+		"username": user.Username,
+	}
+	if c.PostForm("verified") == "true" {
+		clauses["verified"] = true
+	}
+	customDomains, err := pg.GetCustomDomains(db, clauses)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	data := make([]Domain, 0)
+	for _, domain := range customDomains {
+		data = append(data, newDomain(domain))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+func HandleCreateDomain(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	host := models.NormalizeHost(c.PostForm("host"))
+	if !models.IsValidHost(host) || host == defaultHost {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid domain",
+		})
+		return
+	}
+
+	existing, err := pg.GetCustomDomain(db, host)
+	if err != nil && err != sql.ErrNoRows {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if existing != nil {
+		// asking again for an own domain changes nothing
+		if existing.Username == user.Username {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data":    newDomain(*existing),
+			})
+			return
+		}
+		// claims nobody verified do not hold the domain
+		if existing.Verified.Valid {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "Domain is already registered",
+			})
+			return
+		}
+	}
+
+	token, err := models.GenerateDomainToken()
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var claimed bool
+	domain := models.CustomDomain{
+		Host:     host,
+		Username: user.Username,
+		Token:    token,
+		Created:  time.Now(),
+	}
+	if existing != nil {
+		claimed, err = pg.ClaimCustomDomain(db, &domain)
+	} else {
+		err = pg.CreateCustomDomain(db, &domain)
+		claimed = err == nil
+		if pg.IsUniqueViolation(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	// someone verified or registered the domain in the meantime
+	if !claimed {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Domain is already registered",
+		})
+		return
+	}
+
+	log.WithField("username", user.Username).
+		WithField("host", host).
+		Info("Registered custom domain")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    newDomain(domain),
+	})
+}
+
+func HandleVerifyDomain(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	domain, ok := getOwnedDomain(c)
+	if !ok {
+		return
+	}
+
+	if !domain.Verified.Valid {
+		if err := Verify(domain); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		domain.Verified = null.TimeFrom(time.Now())
+		if err := pg.VerifyCustomDomain(db, domain.Host, domain.Verified.Time); err != nil {
+			c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		log.WithField("host", domain.Host).Info("Verified custom domain")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    newDomain(*domain),
+	})
+}
+
+func HandleDeleteDomain(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	domain, ok := getOwnedDomain(c)
+	if !ok {
+		return
+	}
+
+	// links on the domain would no longer resolve, including those in the
+	// trash that can still be restored
+	var count int
+	for _, deleted := range []bool{false, true} {
+		n, err := pg.GetURLCount(db, map[string]interface{}{
+			"domain":  domain.Host,
+			"deleted": deleted,
+		})
+		if err != nil {
+			c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		count += n
+	}
+	if count > 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Delete the links of the domain first",
+		})
+		return
+	}
+
+	if _, err := pg.DeleteCustomDomain(db, domain.Host, domain.Username); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	log.WithField("host", domain.Host).Info("Deleted custom domain")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// getOwnedDomain returns the domain of the host form field if the user owns it
+// or is an admin, and aborts the request otherwise
+func getOwnedDomain(c *gin.Context) (*models.CustomDomain, bool) {
+	db := middleware.GetDB(c)
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return nil, false
+	}
+
+	domain, err := pg.GetCustomDomain(db, models.NormalizeHost(c.PostForm("host")))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"success": false,
+			})
+			return nil, false
+		}
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+
+	if user.Role != models.RoleAdmin && domain.Username != user.Username {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"success": false,
+		})
+		return nil, false
+	}
+	return domain, true
+}
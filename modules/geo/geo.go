@@ -0,0 +1,95 @@
+package geo
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oschwald/geoip2-golang"
+	log "github.com/sirupsen/logrus"
+)
+
+const XForwardedHeader = "X-Forwarded-For"
+
+// Database is the GeoLite2 City database, set with $GEOIP_DATABASE
+var Database = "static/GeoLite2-City.mmdb"
+
+var (
+	once    sync.Once
+	reader  *geoip2.Reader
+	openErr error
+)
+
+// Location is where an ip is, as ISO codes. Both are empty when it is unknown.
+type Location struct {
+	Country string
+	Region  string
+}
+
+func init() {
+	if database := os.Getenv("GEOIP_DATABASE"); database != "" {
+		Database = database
+	}
+}
+
+// Reader returns the database, opened on first use and shared by the web
+// requests and the worker
+func Reader() (*geoip2.Reader, error) {
+	once.Do(func() {
+		if reader, openErr = geoip2.Open(Database); openErr != nil {
+			log.WithField("database", Database).WithError(openErr).Error("error opening geoip2 database")
+		}
+	})
+	return reader, openErr
+}
+
+// lookup finds the location of an ip in the database
+var lookup = func(ip net.IP) (Location, error) {
+	db, err := Reader()
+	if err != nil {
+		return Location{}, nil
+	}
+
+	record, err := db.City(ip)
+	if err != nil {
+		return Location{}, err
+	}
+	location := Location{Country: record.Country.IsoCode}
+	if len(record.Subdivisions) != 0 {
+		location.Region = record.Subdivisions[0].IsoCode
+	}
+	return location, nil
+}
+
+// Lookup returns the location of an ip. Lookup errors are logged and give an
+// unknown location.
+func Lookup(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}
+	}
+	location, err := lookup(parsed)
+	if err != nil {
+		log.WithField("ip", ip).WithError(err).Error("Error Getting Geo Info")
+		return Location{}
+	}
+	return location
+}
+
+// ClientIP returns the ip of the visitor, the first address of
+// X-Forwarded-For when the request went through proxies
+func ClientIP(c *gin.Context) string {
+	if forwarded := c.GetHeader(XForwardedHeader); forwarded != "" {
+		ip := strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		if net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+	if ip := c.ClientIP(); net.ParseIP(ip) != nil {
+		return ip
+	}
+	ip, _, _ := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	return ip
+}
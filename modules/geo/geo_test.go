@@ -0,0 +1,38 @@
+package geo
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	defer func(l func(net.IP) (Location, error)) { lookup = l }(lookup)
+	lookup = func(ip net.IP) (Location, error) {
+		if ip.Equal(net.ParseIP("81.169.145.1")) {
+			return Location{Country: "DE", Region: "BE"}, nil
+		}
+		return Location{}, errors.New("not found")
+	}
+
+	assert.Equal(t, Location{Country: "DE", Region: "BE"}, Lookup("81.169.145.1"))
+	assert.Equal(t, Location{}, Lookup("10.0.0.1"))
+	assert.Equal(t, Location{}, Lookup("not an ip"))
+}
+
+func TestClientIP(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "10.0.0.2:1234"
+
+	c.Request.Header.Set(XForwardedHeader, "81.169.145.1, 10.0.0.1")
+	assert.Equal(t, "81.169.145.1", ClientIP(c))
+
+	c.Request.Header.Set(XForwardedHeader, "unknown")
+	assert.Equal(t, "10.0.0.2", ClientIP(c))
+}
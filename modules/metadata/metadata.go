@@ -0,0 +1,345 @@
+package metadata
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	url2 "net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/jasontthai/tinyalias/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// Timeout is how long fetching a page may take
+	Timeout = 5 * time.Second
+	// MaxBodySize is how much of a page is read looking for its metadata
+	MaxBodySize = 512 * 1024
+	// MaxRobotsSize is how much of a robots.txt is read
+	MaxRobotsSize = 64 * 1024
+	// MaxRedirects is how many redirects are followed to reach a page
+	MaxRedirects = 5
+
+	MaxTitleLength       = 300
+	MaxDescriptionLength = 1000
+	MaxURLLength         = 2048
+
+	// Robot is how the fetcher is named in robots.txt rules
+	Robot     = "TinyAliasBot"
+	UserAgent = Robot + "/1.0 (+https://tinyalias.com)"
+)
+
+// ErrDisallowed is returned for pages robots.txt does not let us fetch
+var ErrDisallowed = fmt.Errorf("Fetching the page is disallowed by robots.txt")
+
+// AllowPrivate lets the fetcher connect to loopback and private networks,
+// which only tests should need
+var AllowPrivate = false
+
+var client = &http.Client{
+	Timeout: Timeout,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: Timeout,
+			Control: denyPrivate,
+		}).DialContext,
+		TLSHandshakeTimeout: Timeout,
+		// pages are fetched once, there is nothing to reuse connections for
+		DisableKeepAlives: true,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= MaxRedirects {
+			return fmt.Errorf("Stopped after %v redirects", MaxRedirects)
+		}
+		return nil
+	},
+}
+
+// denyPrivate refuses connections to addresses that are not on the public
+// internet so destinations cannot make us reach internal services
+func denyPrivate(network, address string, c syscall.RawConn) error {
+	if AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || isPrivate(ip) {
+		return fmt.Errorf("Refusing to connect to %v", host)
+	}
+	return nil
+}
+
+var privateNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+func isPrivate(ip net.IP) bool {
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch reads the metadata of the html page at url, unless robots.txt
+// disallows it
+func Fetch(url string) (*models.Metadata, error) {
+	page, err := url2.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	if page.Scheme != "http" && page.Scheme != "https" {
+		return nil, fmt.Errorf("Cannot fetch %v urls", page.Scheme)
+	}
+
+	allowed, err := robotsAllowed(page)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrDisallowed
+	}
+
+	res, err := get(page.String(), "text/html")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK ||
+		!strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		return &models.Metadata{Fetched: null.TimeFrom(time.Now())}, nil
+	}
+	metadata := Parse(io.LimitReader(res.Body, MaxBodySize), res.Request.URL)
+	metadata.Fetched = null.TimeFrom(time.Now())
+	return metadata, nil
+}
+
+func get(url, accept string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", accept)
+
+	res, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody releases the context of a request once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// robotsAllowed reads the robots.txt of the host of page. A missing
+// robots.txt allows everything.
+func robotsAllowed(page *url2.URL) (bool, error) {
+	robots := &url2.URL{Scheme: page.Scheme, Host: page.Host, Path: "/robots.txt"}
+	res, err := get(robots.String(), "text/plain")
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		path := page.EscapedPath()
+		if page.RawQuery != "" {
+			path += "?" + page.RawQuery
+		}
+		return Allowed(io.LimitReader(res.Body, MaxRobotsSize), Robot, path), nil
+	case res.StatusCode >= 500:
+		// the site may not want to be crawled while it is down
+		return false, fmt.Errorf("robots.txt returned %v", res.StatusCode)
+	default:
+		return true, nil
+	}
+}
+
+// Allowed tells whether the rules of a robots.txt let robot fetch path. The
+// group of the robot wins over the * group and the longest matching rule
+// wins within a group, allowing on ties.
+func Allowed(robots io.Reader, robot, path string) bool {
+	type rule struct {
+		allow  bool
+		prefix string
+	}
+	groups := make(map[string][]rule)
+	var agents []string
+	var inRules bool
+
+	scanner := bufio.NewScanner(robots)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			for _, agent := range agents {
+				groups[agent] = append(groups[agent], rule{allow: key == "allow", prefix: value})
+			}
+		}
+	}
+
+	rules, ok := groups[strings.ToLower(robot)]
+	if !ok {
+		rules = groups["*"]
+	}
+	allowed, longest := true, -1
+	for _, r := range rules {
+		if !strings.HasPrefix(path, r.prefix) {
+			continue
+		}
+		if len(r.prefix) > longest || (len(r.prefix) == longest && r.allow) {
+			allowed, longest = r.allow, len(r.prefix)
+		}
+	}
+	return allowed
+}
+
+// Parse reads the metadata from the head of an html page. Links are resolved
+// against base.
+func Parse(r io.Reader, base *url2.URL) *models.Metadata {
+	metadata := &models.Metadata{}
+	var ogTitle, ogDescription string
+
+	tokenizer := html.NewTokenizer(r)
+loop:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Title:
+				if metadata.Title == "" && tokenizer.Next() == html.TextToken {
+					metadata.Title = tokenizer.Token().Data
+				}
+			case atom.Meta:
+				name := strings.ToLower(attr(token, "name"))
+				if name == "" {
+					name = strings.ToLower(attr(token, "property"))
+				}
+				content := attr(token, "content")
+				switch name {
+				case "description":
+					metadata.Description = content
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "og:image", "og:image:url", "twitter:image":
+					if metadata.Image == "" {
+						metadata.Image = resolve(base, content)
+					}
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attr(token, "rel"))) {
+					if rel == "icon" && metadata.Favicon == "" {
+						metadata.Favicon = resolve(base, attr(token, "href"))
+					}
+				}
+			case atom.Body:
+				break loop
+			}
+		}
+	}
+
+	if metadata.Title == "" {
+		metadata.Title = ogTitle
+	}
+	if metadata.Description == "" {
+		metadata.Description = ogDescription
+	}
+	if metadata.Favicon == "" && base != nil {
+		metadata.Favicon = resolve(base, "/favicon.ico")
+	}
+	metadata.Title = truncate(metadata.Title, MaxTitleLength)
+	metadata.Description = truncate(metadata.Description, MaxDescriptionLength)
+	return metadata
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// resolve returns ref as an absolute http(s) url, or empty if it is not one
+func resolve(base *url2.URL, ref string) string {
+	parsed, err := url2.Parse(strings.TrimSpace(ref))
+	if err != nil || ref == "" {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.String()) > MaxURLLength {
+		return ""
+	}
+	return parsed.String()
+}
+
+// truncate collapses the whitespace of s and cuts it to max characters
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
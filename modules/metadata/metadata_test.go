@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	url2 "net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url2.Parse("https://example.com/docs/page")
+
+	metadata := Parse(strings.NewReader(`<html><head><title>
+		Example   Domain </title></head><body><title>Not this</title></body></html>`), base)
+	assert.Equal(t, "Example Domain", metadata.Title)
+	assert.Equal(t, "https://example.com/favicon.ico", metadata.Favicon)
+
+	metadata = Parse(strings.NewReader(`<html><head>
+		<meta property="og:title" content="OG Title">
+		<meta property="og:description" content="OG Description">
+		<meta property="og:image" content="/images/card.png">
+		<link rel="shortcut icon" href="icon.png">
+		</head></html>`), base)
+	assert.Equal(t, "OG Title", metadata.Title)
+	assert.Equal(t, "OG Description", metadata.Description)
+	assert.Equal(t, "https://example.com/images/card.png", metadata.Image)
+	assert.Equal(t, "https://example.com/docs/icon.png", metadata.Favicon)
+
+	metadata = Parse(strings.NewReader(`<head><title>Title</title>
+		<meta name="description" content="Description">
+		<meta property="og:description" content="OG Description">
+		<meta property="og:image" content="javascript:alert(1)">`), base)
+	assert.Equal(t, "Title", metadata.Title)
+	assert.Equal(t, "Description", metadata.Description)
+	assert.Equal(t, "", metadata.Image)
+
+	metadata = Parse(strings.NewReader(`<title>`+strings.Repeat("a", MaxTitleLength+10)+`</title>`), base)
+	assert.Equal(t, MaxTitleLength, len(metadata.Title))
+
+	metadata = Parse(strings.NewReader(`<p>no head`), nil)
+	assert.Equal(t, "", metadata.Title)
+	assert.Equal(t, "", metadata.Favicon)
+}
+
+func TestAllowed(t *testing.T) {
+	robots := `
+User-agent: *
+Disallow: /private
+Allow: /private/open
+
+User-agent: OtherBot
+User-agent: TinyAliasBot # ours
+Disallow: /
+Allow: /public
+`
+	assert.True(t, Allowed(strings.NewReader(robots), "GoogleBot", "/page"))
+	assert.False(t, Allowed(strings.NewReader(robots), "GoogleBot", "/private/page"))
+	assert.True(t, Allowed(strings.NewReader(robots), "GoogleBot", "/private/open/page"))
+	assert.False(t, Allowed(strings.NewReader(robots), Robot, "/page"))
+	assert.True(t, Allowed(strings.NewReader(robots), Robot, "/public/page"))
+	assert.True(t, Allowed(strings.NewReader(""), Robot, "/page"))
+	assert.True(t, Allowed(strings.NewReader("User-agent: *\nDisallow:\n"), Robot, "/page"))
+}
+
+func TestFetch(t *testing.T) {
+	AllowPrivate = true
+	defer func() { AllowPrivate = false }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, UserAgent, r.UserAgent())
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: TinyAliasBot\nDisallow: /private\n")
+		case "/file":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF")
+		case "/moved":
+			http.Redirect(w, r, "/docs/", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<title>Example</title><meta name="description" content="An example">
+				<link rel="icon" href="icon.png"><meta property="og:image" content="card.png">`)
+		}
+	}))
+	defer server.Close()
+
+	metadata, err := Fetch(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "Example", metadata.Title)
+	assert.Equal(t, "An example", metadata.Description)
+	assert.Equal(t, server.URL+"/icon.png", metadata.Favicon)
+	assert.Equal(t, server.URL+"/card.png", metadata.Image)
+	assert.True(t, metadata.Fetched.Valid)
+
+	metadata, err = Fetch(server.URL + "/moved")
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/docs/icon.png", metadata.Favicon)
+
+	metadata, err = Fetch(server.URL + "/file")
+	assert.Nil(t, err)
+	assert.Equal(t, "", metadata.Title)
+	assert.True(t, metadata.Fetched.Valid)
+
+	_, err = Fetch(server.URL + "/private/page")
+	assert.Equal(t, ErrDisallowed, err)
+
+	_, err = Fetch("ftp://example.com/file")
+	assert.NotNil(t, err)
+
+	AllowPrivate = false
+	_, err = Fetch(server.URL)
+	assert.NotNil(t, err)
+}
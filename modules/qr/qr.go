@@ -0,0 +1,145 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"net/url"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	DefaultSize = 256
+	MinSize     = 64
+	MaxSize     = 2048
+)
+
+// levels are the error correction levels by their QR code letter
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options are how a QR code is rendered
+type Options struct {
+	Format     string
+	Size       int
+	Level      string
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions render a black on white PNG QR code
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       DefaultSize,
+		Level:      "M",
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// ParseOptions reads the format, size, level, fg and bg query parameters,
+// e.g. ?format=svg&size=512&level=H&fg=1a2b3c&bg=ffffff
+func ParseOptions(query url.Values) (Options, error) {
+	options := DefaultOptions()
+	if format := strings.ToLower(query.Get("format")); format != "" {
+		if format != FormatPNG && format != FormatSVG {
+			return options, fmt.Errorf("format must be %v or %v", FormatPNG, FormatSVG)
+		}
+		options.Format = format
+	}
+	if size := query.Get("size"); size != "" {
+		i, err := strconv.Atoi(size)
+		if err != nil || i < MinSize || i > MaxSize {
+			return options, fmt.Errorf("size must be between %v and %v pixels", MinSize, MaxSize)
+		}
+		options.Size = i
+	}
+	if level := strings.ToUpper(query.Get("level")); level != "" {
+		if _, ok := levels[level]; !ok {
+			return options, fmt.Errorf("level must be L, M, Q or H")
+		}
+		options.Level = level
+	}
+	var err error
+	if fg := query.Get("fg"); fg != "" {
+		if options.Foreground, err = parseColor(fg); err != nil {
+			return options, err
+		}
+	}
+	if bg := query.Get("bg"); bg != "" {
+		if options.Background, err = parseColor(bg); err != nil {
+			return options, err
+		}
+	}
+	return options, nil
+}
+
+// parseColor reads a hex color like 1a2b3c or #1a2b3c
+func parseColor(hex string) (color.RGBA, error) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("Invalid color %v. Use hex colors like 1a2b3c", hex)
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("Invalid color %v. Use hex colors like 1a2b3c", hex)
+	}
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, nil
+}
+
+// ContentType is the media type of the rendered QR code
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encodes content as a QR code
+func Render(content string, options Options) ([]byte, error) {
+	code, err := qrcode.New(content, levels[options.Level])
+	if err != nil {
+		return nil, err
+	}
+	code.ForegroundColor = options.Foreground
+	code.BackgroundColor = options.Background
+
+	if options.Format == FormatSVG {
+		return svg(code.Bitmap(), options), nil
+	}
+	return code.PNG(options.Size)
+}
+
+// svg draws every dark module of a QR code as a unit square of one path
+func svg(bitmap [][]bool, options Options) []byte {
+	var buf bytes.Buffer
+	n := len(bitmap)
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v" viewBox="0 0 %v %v" shape-rendering="crispEdges">`,
+		options.Size, options.Size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%v" height="%v" fill="%v"/>`, n, n, hexColor(options.Background))
+	fmt.Fprintf(&buf, `<path fill="%v" d="`, hexColor(options.Foreground))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%v %vh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
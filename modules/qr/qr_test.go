@@ -0,0 +1,58 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	options, err := ParseOptions(url.Values{})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultOptions(), options)
+
+	options, err = ParseOptions(url.Values{
+		"format": {"SVG"},
+		"size":   {"512"},
+		"level":  {"h"},
+		"fg":     {"#1a2b3c"},
+		"bg":     {"ffffff"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, FormatSVG, options.Format)
+	assert.Equal(t, 512, options.Size)
+	assert.Equal(t, "H", options.Level)
+	assert.Equal(t, color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}, options.Foreground)
+
+	for _, query := range []url.Values{
+		{"format": {"gif"}},
+		{"size": {"10"}},
+		{"size": {"big"}},
+		{"level": {"X"}},
+		{"fg": {"red"}},
+	} {
+		_, err := ParseOptions(query)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	options := DefaultOptions()
+	content, err := Render("https://tinyalias.com/example?ta_source=qr", options)
+	assert.Nil(t, err)
+	img, err := png.Decode(bytes.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultSize, img.Bounds().Dx())
+
+	options.Format = FormatSVG
+	content, err = Render("https://tinyalias.com/example?ta_source=qr", options)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "<svg"))
+	assert.Contains(t, string(content), `width="256"`)
+	assert.Contains(t, string(content), `fill="#000000"`)
+}
@@ -2,6 +2,7 @@ package queue
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/bgentry/que-go"
 	"github.com/jackc/pgx"
@@ -13,17 +14,44 @@ const (
 	DetectSpamJob      = "DetectSpamJob"
 	ExpirationJob      = "ExpirationJob"
 	RemovePendingJob   = "RemovePendingJob"
+	PurgeTrashJob      = "PurgeTrashJob"
+	ScheduleJob        = "ScheduleJob"
+	FetchMetadataJob   = "FetchMetadataJob"
 )
 
 type ParseGeoRequest struct {
-	IP   string `json:"ip"`
-	Slug string `json:"slug"`
+	IP     string `json:"ip"`
+	Domain string `json:"domain"`
+	Slug   string `json:"slug"`
+	// Variant is the A/B variant the click went to, if any
+	Variant string `json:"variant,omitempty"`
+	// Source and Medium are where the click came from, e.g. a QR code scan
+	// or twitter / social. Campaign is its utm_campaign.
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	// Bot is why the click was told apart as a bot, empty for humans. Bot
+	// clicks only count in the stats when Counted says so.
+	Bot     string `json:"bot,omitempty"`
+	Counted bool   `json:"counted,omitempty"`
+
+	// the rest describes the click for its event
+	Clicked   time.Time `json:"clicked"`
+	IPHash    string    `json:"ip_hash,omitempty"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Language  string    `json:"language,omitempty"`
 }
 
 type DetectSpamRequest struct {
 	URL string `json:"url"`
 }
 
+type FetchMetadataRequest struct {
+	Domain string `json:"domain"`
+	Slug   string `json:"slug"`
+}
+
 func DispatchParseGeoRequestJob(qc *que.Client, request ParseGeoRequest) error {
 	enc, err := json.Marshal(request)
 	if err != nil {
@@ -71,6 +99,41 @@ func DispatchRemovePendingJob(qc *que.Client) error {
 	return errors.Wrap(qc.Enqueue(&j), "Enqueueing Job")
 }
 
+func DispatchPurgeTrashJob(qc *que.Client) error {
+	j := que.Job{
+		Type: PurgeTrashJob,
+		Args: nil,
+	}
+	return errors.Wrap(qc.Enqueue(&j), "Enqueueing Job")
+}
+
+// DispatchScheduleJob dispatches a job to que-go to apply the scheduled
+// destination changes that are due
+func DispatchScheduleJob(qc *que.Client) error {
+	j := que.Job{
+		Type: ScheduleJob,
+		Args: nil,
+	}
+	return errors.Wrap(qc.Enqueue(&j), "Enqueueing Job")
+}
+
+// DispatchFetchMetadataJob dispatches a job to que-go to fetch the title,
+// description, favicon and image of the destination of a url
+func DispatchFetchMetadataJob(qc *que.Client, domain, slug string) error {
+	request := FetchMetadataRequest{Domain: domain, Slug: slug}
+	enc, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "Marshalling the FetchMetadataJob")
+	}
+
+	j := que.Job{
+		Type: FetchMetadataJob,
+		Args: enc,
+	}
+
+	return errors.Wrap(qc.Enqueue(&j), "Enqueueing Job")
+}
+
 // GetPgxPool based on the provided database URL
 func GetPgxPool(dbURL string) (*pgx.ConnPool, error) {
 	pgxcfg, err := pgx.ParseURI(dbURL)
@@ -0,0 +1,53 @@
+package slugs
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/pg"
+	"github.com/jmoiron/sqlx"
+)
+
+// routes are the slugs served by the application itself
+var routes = []string{
+	"v2", "api", "create", "status", "shorten", "favicon.ico", "robots.txt", "wakemydyno.txt",
+	"analytics", "privacy-policy", "news", "auth", "logout", "login", "register",
+	"update-password", "del", "edit", "restore", "revisions", "schedules", "get", "signal", "keys",
+	"reserved", "static", "preview", "timeseries", "domains",
+}
+
+var builtins = make(map[string]bool)
+
+func init() {
+	for _, route := range routes {
+		builtins[route] = true
+	}
+}
+
+// Routes returns the built-in reserved slugs
+func Routes() []string {
+	return routes
+}
+
+// Reserved returns why a slug cannot be used as an alias, or nil if it can.
+// Slugs are matched case-insensitively against the built-in routes and the
+// reserved_slugs table.
+func Reserved(db *sqlx.DB, slug string) (*models.ReservedSlug, error) {
+	slug = strings.ToLower(slug)
+	if builtins[slug] {
+		return &models.ReservedSlug{
+			Slug:   slug,
+			Reason: models.ReservedRoute,
+		}, nil
+	}
+
+	reserved, err := pg.GetReservedSlug(db, slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return reserved, nil
+}
@@ -0,0 +1,146 @@
+package slugs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/pg"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	Random     = "random"
+	Sequential = "sequential"
+	Words      = "words"
+	Hash       = "hash"
+
+	// MaxAttempts is how many slugs are tried before giving up on a collision
+	MaxAttempts = 10
+
+	randomSize = 6
+	hashSize   = 7
+	syllables  = 3
+)
+
+// Generator generates candidate slugs for a url. attempt is the number of
+// slugs of the url that already collided, so deterministic generators can
+// return a different candidate.
+type Generator interface {
+	Generate(db *sqlx.DB, url string, attempt int) (string, error)
+}
+
+var generators = map[string]Generator{
+	Random:     randomGenerator{},
+	Sequential: sequentialGenerator{},
+	Words:      wordsGenerator{},
+	Hash:       hashGenerator{},
+}
+
+// Default is the strategy of the deployment, set with $SLUG_STRATEGY
+var Default = Random
+
+func init() {
+	if strategy := os.Getenv("SLUG_STRATEGY"); strategy != "" {
+		if _, ok := generators[strategy]; ok {
+			Default = strategy
+		}
+	}
+}
+
+// Get returns the generator of a strategy, or of Default if strategy is empty
+func Get(strategy string) (Generator, error) {
+	if strategy == "" {
+		strategy = Default
+	}
+	generator, ok := generators[strategy]
+	if !ok {
+		return nil, fmt.Errorf("Unknown slug strategy %v. Use one of: %v", strategy, strings.Join(Strategies(), ", "))
+	}
+	return generator, nil
+}
+
+func Strategies() []string {
+	return []string{Random, Sequential, Words, Hash}
+}
+
+// randomGenerator returns random slugs, one character longer every few
+// collisions to get out of crowded spaces
+type randomGenerator struct{}
+
+func (randomGenerator) Generate(db *sqlx.DB, url string, attempt int) (string, error) {
+	return models.GenerateSlug(randomSize + attempt/3), nil
+}
+
+// sequentialGenerator encodes the next value of a database sequence
+type sequentialGenerator struct{}
+
+func (sequentialGenerator) Generate(db *sqlx.DB, url string, attempt int) (string, error) {
+	n, err := pg.NextSlugSequence(db)
+	if err != nil {
+		return "", err
+	}
+	return models.EncodeSlug(uint64(n)), nil
+}
+
+// wordsGenerator returns pronounceable slugs made of consonant-vowel syllables
+type wordsGenerator struct{}
+
+const (
+	consonants = "bdfghjklmnprstvz"
+	vowels     = "aeiou"
+)
+
+func (wordsGenerator) Generate(db *sqlx.DB, url string, attempt int) (string, error) {
+	var slug string
+	for i := 0; i < syllables+attempt/3; i++ {
+		slug += string(consonants[rand.Intn(len(consonants))]) + string(vowels[rand.Intn(len(vowels))])
+	}
+	return slug, nil
+}
+
+// hashGenerator derives the slug from the url so the same url gets the same
+// slug. Collisions hash the url again together with the attempt.
+type hashGenerator struct{}
+
+func (hashGenerator) Generate(db *sqlx.DB, url string, attempt int) (string, error) {
+	if attempt > 0 {
+		url = fmt.Sprintf("%v#%v", url, attempt)
+	}
+	sum := sha256.Sum256([]byte(url))
+	encoded := models.EncodeSlug(binary.BigEndian.Uint64(sum[:8]))
+	for len(encoded) < hashSize {
+		encoded = "1" + encoded
+	}
+	return encoded[:hashSize], nil
+}
+
+// Variants returns aliases similar to slug to suggest when it is taken
+func Variants(slug string) []string {
+	variants := []string{
+		slug + "2",
+		slug + "-2",
+		slug + "-3",
+	}
+	if !strings.HasSuffix(slug, "s") {
+		variants = append(variants, slug+"s")
+	}
+	if strings.Contains(slug, "-") {
+		variants = append(variants, strings.Replace(slug, "-", "_", -1))
+	} else if strings.Contains(slug, "_") {
+		variants = append(variants, strings.Replace(slug, "_", "-", -1))
+	}
+	variants = append(variants,
+		"my-"+slug,
+		"the-"+slug,
+		"get-"+slug,
+		slug+"-link",
+		slug+"-"+models.GenerateSlug(3),
+		slug+"-"+models.GenerateSlug(3),
+	)
+	return variants
+}
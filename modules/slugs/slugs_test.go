@@ -0,0 +1,84 @@
+package slugs
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	for _, strategy := range Strategies() {
+		generator, err := Get(strategy)
+		assert.Nil(t, err)
+		assert.NotNil(t, generator)
+	}
+
+	generator, err := Get("")
+	assert.Nil(t, err)
+	assert.Equal(t, generators[Default], generator)
+
+	_, err = Get("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestRandomGenerator(t *testing.T) {
+	slug, err := randomGenerator{}.Generate(nil, "https://example.com", 0)
+	assert.Nil(t, err)
+	assert.Len(t, slug, randomSize)
+
+	slug, err = randomGenerator{}.Generate(nil, "https://example.com", 3)
+	assert.Nil(t, err)
+	assert.Len(t, slug, randomSize+1)
+}
+
+func TestWordsGenerator(t *testing.T) {
+	slug, err := wordsGenerator{}.Generate(nil, "https://example.com", 0)
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile("^([bdfghjklmnprstvz][aeiou]){3}$"), slug)
+
+	slug, err = wordsGenerator{}.Generate(nil, "https://example.com", 3)
+	assert.Nil(t, err)
+	assert.Len(t, slug, 2*(syllables+1))
+}
+
+func TestHashGenerator(t *testing.T) {
+	first, err := hashGenerator{}.Generate(nil, "https://example.com", 0)
+	assert.Nil(t, err)
+	assert.Len(t, first, hashSize)
+
+	second, err := hashGenerator{}.Generate(nil, "https://example.com", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	other, err := hashGenerator{}.Generate(nil, "https://example.org", 0)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, other)
+
+	retry, err := hashGenerator{}.Generate(nil, "https://example.com", 1)
+	assert.Nil(t, err)
+	assert.Len(t, retry, hashSize)
+	assert.NotEqual(t, first, retry)
+}
+
+func TestReservedRoutes(t *testing.T) {
+	for _, slug := range []string{"analytics", "API", "Shorten", "v2", "domains"} {
+		reserved, err := Reserved(nil, slug)
+		assert.Nil(t, err)
+		assert.Equal(t, models.ReservedRoute, reserved.Reason)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	variants := Variants("my_link")
+	assert.Contains(t, variants, "my_link2")
+	assert.Contains(t, variants, "my_links")
+	assert.Contains(t, variants, "my-link")
+	assert.Contains(t, variants, "my-my_link")
+	assert.NotContains(t, variants, "my_link")
+
+	variants = Variants("news")
+	assert.NotContains(t, variants, "newss")
+	assert.Contains(t, variants, "news-link")
+}
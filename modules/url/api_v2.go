@@ -0,0 +1,580 @@
+package url
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/modules/domains"
+	"github.com/jasontthai/tinyalias/modules/utils"
+	"github.com/jasontthai/tinyalias/pg"
+	log "github.com/sirupsen/logrus"
+)
+
+// Machine-readable error codes returned by the v2 API
+const (
+	ErrCodeInvalidRequest = "invalid_request"
+	ErrCodeUnauthorized   = "unauthorized"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeNotFound       = "not_found"
+	ErrCodeConflict       = "conflict"
+	ErrCodeInternal       = "internal_error"
+)
+
+var (
+	errUnauthorized = errors.New("You have to be logged in")
+	errForbidden    = errors.New("You do not own this link")
+	errNotFound     = errors.New("Link does not exist")
+)
+
+type APIError struct {
+	Code        string   `json:"code"`
+	Message     string   `json:"message"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// APIV2Response is the envelope of every v2 API response
+type APIV2Response struct {
+	Success bool        `json:"success"`
+	Error   *APIError   `json:"error,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Link is the v2 API representation of a url
+type Link struct {
+	Slug              string `json:"slug"`
+	Domain            string `json:"domain,omitempty"`
+	Short             string `json:"short"`
+	QRCode            string `json:"qr_code"`
+	Original          string `json:"original"`
+	Status            string `json:"status"`
+	Counter           int    `json:"counter"`
+	PasswordProtected bool   `json:"password_protected"`
+	Mindful           bool   `json:"mindful"`
+	Expiration        int64  `json:"expiration,omitempty"`
+	Username          string `json:"username,omitempty"`
+	Created           int64  `json:"created"`
+	Updated           int64  `json:"updated,omitempty"`
+	Deleted           int64  `json:"deleted,omitempty"`
+	MaxClicks         int    `json:"max_clicks,omitempty"`
+	NotBefore         int64  `json:"not_before,omitempty"`
+	ForwardQuery      string `json:"forward_query,omitempty"`
+	ForwardPath       bool   `json:"forward_path"`
+	RedirectCode      int    `json:"redirect_code"`
+	CacheControl      string `json:"cache_control,omitempty"`
+	NoIndex           bool   `json:"no_index"`
+	ReferrerPolicy    string `json:"referrer_policy,omitempty"`
+	Description       string `json:"description,omitempty"`
+
+	Targets    models.TargetRules `json:"targets"`
+	GeoTargets models.GeoRules    `json:"geo_targets"`
+	Variants   models.Variants    `json:"variants"`
+	Metadata   models.Metadata    `json:"metadata"`
+	Card       models.Card        `json:"card"`
+}
+
+type LinkStats struct {
+	Slug      string                     `json:"slug"`
+	Clicks    int                        `json:"clicks"`
+	Analytics []models.Analytics         `json:"analytics"`
+	Variants  []models.VariantAnalytics  `json:"variants"`
+	Sources   []models.SourceAnalytics   `json:"sources"`
+	Campaigns []models.CampaignAnalytics `json:"campaigns"`
+	Devices   models.DeviceAnalytics     `json:"devices"`
+	Bots      []models.BotAnalytics      `json:"bots"`
+}
+
+type CreateLinkRequest struct {
+	URL        string `json:"url"`
+	Alias      string `json:"alias"`
+	Password   string `json:"password"`
+	Expiration int64  `json:"expiration"`
+	Mindful    bool   `json:"mindful"`
+	MaxClicks  int    `json:"max_clicks"`
+	NotBefore  int64  `json:"not_before"`
+	Domain     string `json:"domain"`
+	Strategy   string `json:"strategy"`
+	OnConflict string `json:"on_conflict"`
+
+	ForwardQuery string `json:"forward_query"`
+	ForwardPath  bool   `json:"forward_path"`
+
+	RedirectCode   int    `json:"redirect_code"`
+	CacheControl   string `json:"cache_control"`
+	NoIndex        bool   `json:"no_index"`
+	ReferrerPolicy string `json:"referrer_policy"`
+	Description    string `json:"description"`
+
+	Targets    models.TargetRules `json:"targets"`
+	GeoTargets models.GeoRules    `json:"geo_targets"`
+	Variants   models.Variants    `json:"variants"`
+	Card       models.Card        `json:"card"`
+}
+
+// UpdateLinkRequest only changes the fields that are present in the body.
+// An expiration or not_before of 0 or an empty password removes it from the link.
+type UpdateLinkRequest struct {
+	URL        *string `json:"url"`
+	Alias      *string `json:"alias"`
+	Password   *string `json:"password"`
+	Expiration *int64  `json:"expiration"`
+	Mindful    *bool   `json:"mindful"`
+	MaxClicks  *int    `json:"max_clicks"`
+	NotBefore  *int64  `json:"not_before"`
+
+	ForwardQuery *string `json:"forward_query"`
+	ForwardPath  *bool   `json:"forward_path"`
+
+	RedirectCode   *int    `json:"redirect_code"`
+	CacheControl   *string `json:"cache_control"`
+	NoIndex        *bool   `json:"no_index"`
+	ReferrerPolicy *string `json:"referrer_policy"`
+	Description    *string `json:"description"`
+
+	Targets    *models.TargetRules `json:"targets"`
+	GeoTargets *models.GeoRules    `json:"geo_targets"`
+	Variants   *models.Variants    `json:"variants"`
+	// Card replaces the whole card, an empty card removes it
+	Card *models.Card `json:"card"`
+}
+
+func NewLink(url *models.URL) Link {
+	link := Link{
+		Slug:              url.Slug,
+		Short:             domains.ShortURL(url.Domain, url.Slug),
+		QRCode:            domains.ShortURL(url.Domain, url.Slug) + QRSuffix,
+		Domain:            url.Domain,
+		Original:          url.Url,
+		Status:            url.Status,
+		Counter:           url.Counter,
+		PasswordProtected: url.Password != "",
+		Mindful:           url.Mindful,
+		MaxClicks:         url.MaxClicks,
+		ForwardQuery:      url.ForwardQuery,
+		ForwardPath:       url.ForwardPath,
+		RedirectCode:      url.RedirectStatus(),
+		CacheControl:      url.CacheControl,
+		NoIndex:           url.NoIndex,
+		ReferrerPolicy:    url.ReferrerPolicy,
+		Description:       url.Description,
+		Card:              url.Card,
+		Targets:           url.Targets,
+		GeoTargets:        url.GeoTargets,
+		Variants:          url.Variants,
+		Metadata:          url.Metadata,
+		Username:          url.Username,
+		Created:           url.Created.Unix(),
+	}
+	if url.Expired.Valid {
+		link.Expiration = url.Expired.Time.Unix()
+	}
+	if url.Updated.Valid {
+		link.Updated = url.Updated.Time.Unix()
+	}
+	if url.Deleted.Valid {
+		link.Deleted = url.Deleted.Time.Unix()
+	}
+	if url.NotBefore.Valid {
+		link.NotBefore = url.NotBefore.Time.Unix()
+	}
+	return link
+}
+
+func APIV2CreateLink(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksWrite); !ok {
+		return
+	}
+
+	var request CreateLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Request body must be valid JSON")
+		return
+	}
+	if request.URL == "" {
+		abortWithAPIError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Missing url")
+		return
+	}
+
+	urlObj, status, err := createURL(c, request)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		c.AbortWithStatusJSON(status, APIV2Response{
+			Success: false,
+			Error: &APIError{
+				Code:        errorCodeFromStatus(status),
+				Message:     err.Error(),
+				Suggestions: suggestions(err),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIV2Response{
+		Success: true,
+		Data:    NewLink(urlObj),
+	})
+}
+
+func APIV2GetLink(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksRead); !ok {
+		return
+	}
+
+	// the destination and settings of a link are only shown to its owner
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    NewLink(urlObj),
+	})
+}
+
+func APIV2UpdateLink(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksWrite); !ok {
+		return
+	}
+
+	var request UpdateLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Request body must be valid JSON")
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	if status, err := updateLink(c, urlObj, request); err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	log.WithField("slug", urlObj.Slug).Info("Updated URL")
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    NewLink(urlObj),
+	})
+}
+
+func APIV2DeleteLink(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksWrite); !ok {
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	if status, err := trashLink(c, urlObj); err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    NewLink(urlObj),
+	})
+}
+
+func APIV2RestoreLink(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksWrite); !ok {
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	if status, err := restoreLink(c, urlObj); err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    NewLink(urlObj),
+	})
+}
+
+// APIV2GetLinks lists the links of the authenticated user, or all links
+// for admins
+func APIV2GetLinks(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	if ok := authenticateAPIRequest(c, models.ScopeLinksRead); !ok {
+		return
+	}
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		abortWithAPIError(c, http.StatusUnauthorized, ErrCodeUnauthorized, errUnauthorized.Error())
+		return
+	}
+
+	limit, offset, err := utils.GetLimitAndOffsetQueries(c)
+	if err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	clauses := make(map[string]interface{})
+	clauses["_limit"] = limit
+	clauses["_offset"] = offset
+	if user.Role != models.RoleAdmin {
+		clauses["username"] = user.Username
+	}
+	if c.Query("trash") == "true" {
+		clauses["deleted"] = true
+	}
+	urls, err := pg.GetURLs(db, clauses)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	links := make([]Link, 0, len(urls))
+	for i := range urls {
+		links = append(links, NewLink(&urls[i]))
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    links,
+	})
+}
+
+func APIV2GetLinkStats(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	if ok := authenticateAPIRequest(c, models.ScopeStatsRead); !ok {
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	clicks, analytics, err := getAnalytics(db, urlObj.Domain, urlObj.Slug)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	variants, err := getVariantAnalytics(db, urlObj.Domain, urlObj.Slug)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	sources, err := getSourceAnalytics(db, urlObj.Domain, urlObj.Slug)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	campaigns, err := getCampaignAnalytics(db, urlObj.Domain, urlObj.Slug)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	devices, err := getDeviceAnalytics(db, urlObj.Domain, urlObj.Slug)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	botHits, err := getBotAnalytics(db, urlObj.Domain, urlObj.Slug)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data: LinkStats{
+			Slug:      urlObj.Slug,
+			Clicks:    clicks,
+			Analytics: analytics,
+			Variants:  variants,
+			Sources:   sources,
+			Campaigns: campaigns,
+			Devices:   devices,
+			Bots:      botHits,
+		},
+	})
+}
+
+// handleV2Routes dispatches GET requests under /v2. They reach us through the
+// /:slug/*path route because a static /v2 route would conflict with /:slug.
+func handleV2Routes(c *gin.Context) {
+	segments := strings.Split(strings.Trim(c.Param("path"), "/"), "/")
+	if len(segments) > 0 && segments[0] == "links" {
+		switch {
+		case len(segments) == 1:
+			APIV2GetLinks(c)
+			return
+		case len(segments) == 2 && segments[1] != "":
+			APIV2GetLink(c)
+			return
+		case len(segments) == 3 && segments[2] == "stats":
+			APIV2GetLinkStats(c)
+			return
+		case len(segments) == 4 && segments[2] == "stats" && segments[3] == "timeseries":
+			APIV2GetLinkTimeSeries(c)
+			return
+		case len(segments) == 3 && segments[2] == "revisions":
+			APIV2GetLinkRevisions(c)
+			return
+		case len(segments) == 3 && segments[2] == "schedules":
+			APIV2GetLinkSchedules(c)
+			return
+		}
+	}
+	abortWithAPIError(c, http.StatusNotFound, ErrCodeNotFound, "Unknown API route")
+}
+
+// v2LinkSlug returns the slug of the link addressed by a v2 request
+func v2LinkSlug(c *gin.Context) string {
+	if c.Param("slug") == "v2" && c.Param("path") != "" {
+		// /links/{slug}[/...]
+		segments := strings.Split(strings.Trim(c.Param("path"), "/"), "/")
+		if len(segments) > 1 {
+			return segments[1]
+		}
+		return ""
+	}
+	return c.Param("slug")
+}
+
+// checkAPIKey authenticates the API key of the request, if any, and returns
+// the response status to use when it is rejected
+func checkAPIKey(c *gin.Context, scope string) (int, error) {
+	err := auth.AuthenticateAPIKey(c, scope)
+	switch err {
+	case nil:
+		return http.StatusOK, nil
+	case auth.ErrInvalidAPIKey:
+		return http.StatusUnauthorized, err
+	case auth.ErrInsufficientScope:
+		return http.StatusForbidden, err
+	default:
+		c.Error(err)
+		return http.StatusInternalServerError, err
+	}
+}
+
+// authenticateAPIRequest is checkAPIKey for the v2 API. The error response
+// has already been written when ok is false. Changes are only made by API keys
+// or anonymously, with JSON bodies a form of another site cannot send.
+func authenticateAPIRequest(c *gin.Context, scope string) bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		auth.IgnoreSession(c)
+		if c.Request.ContentLength != 0 && c.ContentType() != gin.MIMEJSON {
+			abortWithAPIError(c, http.StatusUnsupportedMediaType, ErrCodeInvalidRequest, "Content-Type must be application/json")
+			return false
+		}
+	}
+
+	status, err := checkAPIKey(c, scope)
+	if err != nil {
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return false
+	}
+	return true
+}
+
+// v2LinkDomain returns the custom domain of the link of a request, empty for
+// links on the default domain
+func v2LinkDomain(c *gin.Context) string {
+	return models.NormalizeHost(c.Query("domain"))
+}
+
+// getOwnedLink looks up a link the authenticated user is allowed to manage.
+// The response has already been written when ok is false.
+func getOwnedLink(c *gin.Context, domain, slug string) (url *models.URL, ok bool) {
+	url, status, err := lookupOwnedLink(c, domain, slug)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return nil, false
+	}
+	return url, true
+}
+
+// lookupOwnedLink returns the link of a slug if the authenticated user owns it
+// or is an admin, leaving the response to the caller
+func lookupOwnedLink(c *gin.Context, domain, slug string) (*models.URL, int, error) {
+	db := middleware.GetDB(c)
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return nil, http.StatusUnauthorized, errUnauthorized
+	}
+
+	url, err := pg.GetURL(db, domain, slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	if user.Role != models.RoleAdmin && url.Username != user.Username {
+		return nil, http.StatusForbidden, errForbidden
+	}
+	return url, http.StatusOK, nil
+}
+
+func abortWithAPIError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, APIV2Response{
+		Success: false,
+		Error: &APIError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+func errorCodeFromStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict, http.StatusGone:
+		return ErrCodeConflict
+	default:
+		return ErrCodeInternal
+	}
+}
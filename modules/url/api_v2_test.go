@@ -0,0 +1,196 @@
+package url
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIV2Links(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/:slug", Get)
+	router.GET("/:slug/*path", Get)
+	router.POST("/v2/links", APIV2CreateLink)
+	router.PATCH("/v2/links/:slug", APIV2UpdateLink)
+	router.DELETE("/v2/links/:slug", APIV2DeleteLink)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"url": "example.com", "alias": "%v", "password": "abc"}`, slug)
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+
+		resp, err := ioutil.ReadAll(w.Body)
+		assert.Nil(t, err, "Failed to read response body.")
+
+		var rawRes map[string]interface{}
+		err = json.Unmarshal(resp, &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		assert.Equal(t, true, rawRes["success"].(bool))
+		data := rawRes["data"].(map[string]interface{})
+		assert.Equal(t, slug, data["slug"].(string))
+		assert.Equal(t, "https://example.com", data["original"].(string))
+		assert.Equal(t, true, data["password_protected"].(bool))
+		assert.Nil(t, data["password"])
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(`{"alias": "abc"}`))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+
+		var rawRes map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		assert.Equal(t, ErrCodeInvalidRequest, rawRes["error"].(map[string]interface{})["code"].(string))
+	}
+	// forms of other sites cannot send JSON
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(`{"url": "example.com"}`))
+		req.Header.Add("Content-Type", "text/plain")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 415, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"url": "example.org", "alias": "%v"}`, slug)
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 409, w.Code)
+
+		var rawRes map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		apiErr := rawRes["error"].(map[string]interface{})
+		assert.Equal(t, ErrCodeConflict, apiErr["code"].(string))
+		assert.Contains(t, apiErr["suggestions"], slug+"2")
+	}
+	{
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"url": "example.org", "alias": "%v", "on_conflict": "fallback"}`, slug)
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+
+		var rawRes map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		assert.NotEqual(t, slug, rawRes["data"].(map[string]interface{})["slug"].(string))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(`{"url": "example.com", "alias": "analytics"}`))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 409, w.Code)
+
+		var rawRes map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		assert.Equal(t, ErrCodeConflict, rawRes["error"].(map[string]interface{})["code"].(string))
+	}
+	// aliases the preview and QR code routes would shadow are rejected
+	for _, alias := range []string{slug + "+", slug + ".qr"} {
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"url": "example.com", "alias": "%v"}`, alias)
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+	// links are not shown to anonymous requests
+	for _, path := range []string{"/v2/links/%v", "/v2/links/%v/stats"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf(path, slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code)
+		assert.NotContains(t, w.Body.String(), "example.com")
+
+		var rawRes map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		assert.Equal(t, ErrCodeUnauthorized, rawRes["error"].(map[string]interface{})["code"].(string))
+		assert.Nil(t, rawRes["data"])
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/v2/links/%v", slug), bytes.NewBufferString(`{"mindful": true}`))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/v2/links/%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v2/links", nil)
+		req.Header.Add("Authorization", "Bearer ta_invalid")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code)
+
+		var rawRes map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &rawRes)
+		assert.Nil(t, err, "Failed to parse response.")
+		assert.Equal(t, ErrCodeUnauthorized, rawRes["error"].(map[string]interface{})["code"].(string))
+	}
+}
+
+func TestAPIV2LinkTargets(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/:slug", Get)
+	router.POST("/v2/links", APIV2CreateLink)
+	router.PATCH("/v2/links/:slug", APIV2UpdateLink)
+	slug := models.GenerateSlug(6)
+	iPhone := "Mozilla/5.0 (iPhone; CPU iPhone OS 12_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+
+	{
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"url": "example.com", "alias": "%v", "targets": [{"platform": "ios", "url": "apps.apple.com/app/example"}]}`, slug)
+		req, _ := http.NewRequest("POST", "/v2/links", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v", slug), nil)
+		req.Header.Add("User-Agent", iPhone)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://apps.apple.com/app/example", w.Header().Get("Location"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	}
+	{
+		w := httptest.NewRecorder()
+		body := `{"targets": [{"platform": "blackberry", "url": "example.org"}]}`
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/v2/links/%v", slug), bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+}
@@ -0,0 +1,40 @@
+package url
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/domains"
+	"github.com/jasontthai/tinyalias/modules/utils"
+)
+
+// sanitizeCard validates the share card of a url
+func sanitizeCard(card models.Card) (models.Card, int, error) {
+	card.Title = strings.TrimSpace(card.Title)
+	card.Description = strings.TrimSpace(card.Description)
+	card.Image = strings.TrimSpace(card.Image)
+	if !card.IsValid() {
+		return card, http.StatusBadRequest, errInvalidCard
+	}
+	return card, http.StatusOK, nil
+}
+
+// wantsCard tells whether a request comes from a bot unfurling a url with a
+// share card. Humans are always redirected.
+func wantsCard(c *gin.Context, url *models.URL) bool {
+	return !url.Card.IsEmpty() && models.IsUnfurlBot(c.GetHeader("User-Agent"))
+}
+
+// renderCard serves the share card of a url to unfurl bots. It is not a
+// click, and neither the destination nor its metadata are shown.
+func renderCard(c *gin.Context, url *models.URL) {
+	c.Header("X-Robots-Tag", "noindex")
+	utils.HandleHtmlResponse(c, http.StatusOK, "card.tmpl.html", gin.H{
+		"short":       domains.ShortURL(url.Domain, url.Slug),
+		"title":       url.Card.Title,
+		"description": url.Card.Description,
+		"image":       url.Card.Image,
+	})
+}
@@ -0,0 +1,64 @@
+package url
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/pg"
+	"github.com/jmoiron/sqlx"
+)
+
+// getDeviceAnalytics returns the clicks of a link by device class, OS and
+// browser
+func getDeviceAnalytics(db *sqlx.DB, domain, slug string) (models.DeviceAnalytics, error) {
+	stats, err := pg.GetURLDeviceStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+	})
+	if err != nil {
+		return models.DeviceAnalytics{}, err
+	}
+	return summarizeDevices(stats), nil
+}
+
+// summarizeDevices adds up the stats of every browser, OS and device class
+// combination into a breakdown of each
+func summarizeDevices(stats []models.URLDeviceStat) models.DeviceAnalytics {
+	var total int
+	devices := make(map[string]int)
+	oses := make(map[string]int)
+	browsers := make(map[string]int)
+	for _, stat := range stats {
+		total += stat.Counter
+		devices[stat.Device] += stat.Counter
+		oses[stat.OS] += stat.Counter
+		browsers[stat.Browser] += stat.Counter
+	}
+
+	return models.DeviceAnalytics{
+		Devices:  breakdown(devices, total),
+		OS:       breakdown(oses, total),
+		Browsers: breakdown(browsers, total),
+	}
+}
+
+// breakdown lists counts most clicked first, with their percentage of total
+// rounded to one decimal
+func breakdown(counts map[string]int, total int) []models.UserAgentAnalytics {
+	analytics := make([]models.UserAgentAnalytics, 0, len(counts))
+	for name, count := range counts {
+		analytics = append(analytics, models.UserAgentAnalytics{
+			Name:    name,
+			Count:   count,
+			Percent: math.Round(float64(count)*1000/float64(total)) / 10,
+		})
+	}
+	sort.Slice(analytics, func(i, j int) bool {
+		if analytics[i].Count != analytics[j].Count {
+			return analytics[i].Count > analytics[j].Count
+		}
+		return analytics[i].Name < analytics[j].Name
+	})
+	return analytics
+}
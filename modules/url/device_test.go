@@ -0,0 +1,32 @@
+package url
+
+import (
+	"testing"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeDevices(t *testing.T) {
+	analytics := summarizeDevices([]models.URLDeviceStat{
+		{Browser: "Chrome", OS: "Android", Device: "Mobile", Counter: 4},
+		{Browser: "Safari", OS: "iOS", Device: "Mobile", Counter: 3},
+		{Browser: "Chrome", OS: "Windows", Device: "Desktop", Counter: 2},
+	})
+	assert.Equal(t, []models.UserAgentAnalytics{
+		{Name: "Mobile", Count: 7, Percent: 77.8},
+		{Name: "Desktop", Count: 2, Percent: 22.2},
+	}, analytics.Devices)
+	assert.Equal(t, []models.UserAgentAnalytics{
+		{Name: "Android", Count: 4, Percent: 44.4},
+		{Name: "iOS", Count: 3, Percent: 33.3},
+		{Name: "Windows", Count: 2, Percent: 22.2},
+	}, analytics.OS)
+	assert.Equal(t, []models.UserAgentAnalytics{
+		{Name: "Chrome", Count: 6, Percent: 66.7},
+		{Name: "Safari", Count: 3, Percent: 33.3},
+	}, analytics.Browsers)
+
+	analytics = summarizeDevices(nil)
+	assert.Equal(t, 0, len(analytics.Devices))
+}
@@ -0,0 +1,86 @@
+package url
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/domains"
+	"github.com/jasontthai/tinyalias/modules/utils"
+)
+
+const (
+	// PreviewSuffix after a slug shows where the link goes instead of
+	// redirecting
+	PreviewSuffix = "+"
+	// PreviewCookie makes a visitor preview every link. PreviewQuery set to
+	// 0 skips the preview for a single visit.
+	PreviewCookie    = "tinyalias_preview"
+	PreviewQuery     = "preview"
+	previewCookieAge = 365 * 24 * 60 * 60
+)
+
+// previewSlug returns the slug requested and whether the visitor asked to
+// preview it
+func previewSlug(c *gin.Context) (string, bool) {
+	slug := c.Param("slug")
+	if len(slug) > len(PreviewSuffix) && strings.HasSuffix(slug, PreviewSuffix) {
+		return strings.TrimSuffix(slug, PreviewSuffix), true
+	}
+
+	// visitors submitting a password already went past the preview
+	if c.Query(PreviewQuery) == "0" || c.Query("password") != "" {
+		return slug, false
+	}
+	preference, err := c.Cookie(PreviewCookie)
+	return slug, err == nil && preference == "true"
+}
+
+// renderPreview shows where a url goes without counting a click. The
+// destination of password protected urls and urls with limited clicks stays
+// hidden.
+func renderPreview(c *gin.Context, url *models.URL) {
+	query := c.Request.URL.Query()
+	query.Set(PreviewQuery, "0")
+	data := gin.H{
+		"short":       domains.ShortURL(url.Domain, url.Slug),
+		"continue":    "/" + url.Slug + "?" + query.Encode(),
+		"description": url.Description,
+		"created":     url.Created.Unix(),
+		"safe":        url.IsSafe(),
+		"status":      url.Status,
+		"protected":   url.Password != "",
+		"limited":     url.MaxClicks > 0,
+		"varies":      len(url.Targets) > 0 || len(url.GeoTargets) > 0 || len(url.Variants) > 0,
+	}
+	if preference, err := c.Cookie(PreviewCookie); err == nil && preference == "true" {
+		data["always"] = true
+	}
+	if !url.IsLaunched(time.Now()) {
+		data["launch"] = url.NotBefore.Time.Unix()
+	}
+
+	// only visitors using up a click of a limited link learn where it goes.
+	// The title is whatever the metadata job stored, previews never fetch.
+	if url.Password == "" && url.MaxClicks == 0 {
+		data["destination"] = url.Url
+		data["title"] = url.Metadata.Title
+	}
+
+	c.Header("X-Robots-Tag", "noindex")
+	utils.HandleHtmlResponse(c, http.StatusOK, "preview.tmpl.html", data)
+}
+
+// HandlePreviewPreference turns previewing every link on or off for a visitor
+func HandlePreviewPreference(c *gin.Context) {
+	if c.PostForm("enable") == "true" {
+		c.SetCookie(PreviewCookie, "true", previewCookieAge, "/", "", false, true)
+	} else {
+		c.SetCookie(PreviewCookie, "", -1, "/", "", false, true)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
@@ -0,0 +1,51 @@
+package url
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/domains"
+	"github.com/jasontthai/tinyalias/modules/qr"
+)
+
+const (
+	// QRSuffix after a slug returns the QR code of the link
+	QRSuffix = ".qr"
+	// SourceQuery marks where a click came from. It is never passed on to
+	// the destination.
+	SourceQuery = "ta_source"
+)
+
+// qrSlug returns the slug and whether its QR code was requested
+func qrSlug(slug string) (string, bool) {
+	if len(slug) > len(QRSuffix) && strings.HasSuffix(slug, QRSuffix) {
+		return strings.TrimSuffix(slug, QRSuffix), true
+	}
+	return slug, false
+}
+
+// renderQRCode responds with the QR code of a url. Scans of the code are
+// counted as clicks from the QR code source.
+func renderQRCode(c *gin.Context, url *models.URL) {
+	options, err := qr.ParseOptions(c.Request.URL.Query())
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	content := domains.ShortURL(url.Domain, url.Slug) + "?" + SourceQuery + "=" + models.ClickSourceQR
+	image, err := qr.Render(content, options)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", "attachment; filename=\""+url.Slug+"."+options.Format+"\"")
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, options.ContentType(), image)
+}
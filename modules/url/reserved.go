@@ -0,0 +1,139 @@
+package url
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/modules/slugs"
+	"github.com/jasontthai/tinyalias/pg"
+	log "github.com/sirupsen/logrus"
+)
+
+// getAdmin aborts the request unless the user is an admin
+func getAdmin(c *gin.Context) *models.User {
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return nil
+	}
+	if user.Role != models.RoleAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"success": false,
+		})
+		return nil
+	}
+	return user
+}
+
+func HandleGetReservedSlugs(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	if user := getAdmin(c); user == nil {
+		return
+	}
+
+	clauses := make(map[string]interface{})
+	if reason := c.PostForm("reason"); reason != "" {
+		clauses["reason"] = reason
+	}
+	reserved, err := pg.GetReservedSlugs(db, clauses)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reserved,
+		"routes":  slugs.Routes(),
+	})
+}
+
+func HandleCreateReservedSlug(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := getAdmin(c)
+	if user == nil {
+		return
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(c.PostForm("slug")))
+	reason := c.PostForm("reason")
+	if slug == "" || !models.IsValidReservedReason(reason) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Missing slug or invalid reason",
+		})
+		return
+	}
+
+	reserved := &models.ReservedSlug{
+		Slug:     slug,
+		Reason:   reason,
+		Username: user.Username,
+		Created:  time.Now(),
+	}
+	if err := pg.CreateReservedSlug(db, reserved); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	log.WithField("username", user.Username).
+		WithField("slug", slug).
+		WithField("reason", reason).
+		Info("Reserved slug")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reserved,
+	})
+}
+
+func HandleDeleteReservedSlug(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	user := getAdmin(c)
+	if user == nil {
+		return
+	}
+
+	slug := strings.ToLower(c.PostForm("slug"))
+	deleted, err := pg.DeleteReservedSlug(db, slug)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	log.WithField("username", user.Username).
+		WithField("slug", slug).
+		Info("Released reserved slug")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
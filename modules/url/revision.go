@@ -0,0 +1,135 @@
+package url
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/modules/utils"
+	"github.com/jasontthai/tinyalias/pg"
+	log "github.com/sirupsen/logrus"
+)
+
+// recordRevision adds an entry to the audit trail of a url. Failing to record
+// it does not fail the request.
+func recordRevision(c *gin.Context, action string, url *models.URL, oldValues, newValues models.PropertyMap) {
+	db := middleware.GetDB(c)
+
+	revision := &models.URLRevision{
+		Domain:    url.Domain,
+		Slug:      url.Slug,
+		Action:    action,
+		Source:    requestSource(c),
+		OldValues: oldValues,
+		NewValues: newValues,
+		Created:   time.Now(),
+	}
+	if user := auth.GetAuthenticatedUser(c); user != nil {
+		revision.Username = user.Username
+	}
+
+	if err := pg.CreateURLRevision(db, revision); err != nil {
+		c.Error(err)
+		log.WithField("slug", url.Slug).
+			WithField("domain", url.Domain).
+			WithField("action", action).
+			WithError(err).Error("error recording url revision")
+	}
+}
+
+// requestSource tells whether a request came through the API or the website
+func requestSource(c *gin.Context) string {
+	hostname := strings.Split(c.Request.Host, ".")
+	if hostname[0] == "api" || c.GetHeader(auth.AuthorizationHeader) != "" ||
+		strings.HasPrefix(c.Request.URL.Path, "/v2/") {
+		return models.SourceAPI
+	}
+	return models.SourceWeb
+}
+
+// getRevisions returns the revisions of a slug the authenticated user may see.
+// Admins can also see the revisions of links that no longer exist.
+func getRevisions(c *gin.Context, domain, slug string) ([]models.URLRevision, int, error) {
+	db := middleware.GetDB(c)
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		return nil, http.StatusUnauthorized, errUnauthorized
+	}
+
+	url, err := pg.GetURL(db, domain, slug)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, http.StatusInternalServerError, err
+	}
+	if user.Role != models.RoleAdmin {
+		if url == nil {
+			return nil, http.StatusNotFound, errNotFound
+		}
+		if url.Username != user.Username {
+			return nil, http.StatusForbidden, errForbidden
+		}
+	}
+
+	limit, offset, err := utils.GetLimitAndOffsetQueries(c)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	revisions, err := pg.GetURLRevisions(db, map[string]interface{}{
+		"domain":  domain,
+		"slug":    slug,
+		"_limit":  limit,
+		"_offset": offset,
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if revisions == nil {
+		revisions = make([]models.URLRevision, 0)
+	}
+	return revisions, http.StatusOK, nil
+}
+
+func APIV2GetLinkRevisions(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksRead); !ok {
+		return
+	}
+
+	revisions, status, err := getRevisions(c, v2LinkDomain(c), v2LinkSlug(c))
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    revisions,
+	})
+}
+
+func HandleGetLinkRevisions(c *gin.Context) {
+	revisions, status, err := getRevisions(c, models.NormalizeHost(c.PostForm("domain")), c.PostForm("slug"))
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    revisions,
+	})
+}
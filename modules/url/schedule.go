@@ -0,0 +1,270 @@
+package url
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/pg"
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxSchedules is how many pending destination changes a url may have
+const MaxSchedules = 20
+
+type CreateScheduleRequest struct {
+	URL       string `json:"url"`
+	Scheduled int64  `json:"scheduled"`
+}
+
+// createSchedule schedules a change of the destination of urlObj, applied by
+// the ScheduleJob once it is due
+func createSchedule(c *gin.Context, urlObj *models.URL, request CreateScheduleRequest) (*models.URLSchedule, int, error) {
+	db := middleware.GetDB(c)
+
+	if urlObj.Deleted.Valid {
+		return nil, http.StatusConflict, fmt.Errorf("Link is in the trash. Restore it first.")
+	}
+	scheduled := time.Unix(request.Scheduled, 0)
+	if request.Scheduled == 0 || !scheduled.After(time.Now()) {
+		return nil, http.StatusBadRequest, fmt.Errorf("Scheduled time must be in the future")
+	}
+	url, status, err := sanitizeURL(c, request.URL)
+	if err != nil {
+		return nil, status, err
+	}
+
+	pending, err := pg.GetURLSchedules(db, map[string]interface{}{
+		"domain":  urlObj.Domain,
+		"slug":    urlObj.Slug,
+		"pending": true,
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if len(pending) >= MaxSchedules {
+		return nil, http.StatusBadRequest, fmt.Errorf("A link may have at most %v scheduled changes", MaxSchedules)
+	}
+
+	schedule := &models.URLSchedule{
+		Domain:    urlObj.Domain,
+		Slug:      urlObj.Slug,
+		URL:       url,
+		Scheduled: scheduled,
+		Created:   time.Now(),
+	}
+	if user := auth.GetAuthenticatedUser(c); user != nil {
+		schedule.Username = user.Username
+	}
+	if err := pg.CreateURLSchedule(db, schedule); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	log.WithField("slug", urlObj.Slug).
+		WithField("url", url).
+		WithField("scheduled", scheduled).
+		Info("Scheduled destination change")
+	return schedule, http.StatusOK, nil
+}
+
+// getSchedules returns the pending and applied destination changes of a url
+func getSchedules(c *gin.Context, urlObj *models.URL) ([]models.URLSchedule, int, error) {
+	db := middleware.GetDB(c)
+
+	schedules, err := pg.GetURLSchedules(db, map[string]interface{}{
+		"domain": urlObj.Domain,
+		"slug":   urlObj.Slug,
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if schedules == nil {
+		schedules = make([]models.URLSchedule, 0)
+	}
+	return schedules, http.StatusOK, nil
+}
+
+// deleteSchedule cancels a pending destination change of a url
+func deleteSchedule(c *gin.Context, urlObj *models.URL, id int64) (int, error) {
+	db := middleware.GetDB(c)
+
+	deleted, err := pg.DeleteURLSchedule(db, urlObj.Domain, urlObj.Slug, id)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !deleted {
+		return http.StatusNotFound, fmt.Errorf("Scheduled change does not exist or was already applied")
+	}
+	return http.StatusOK, nil
+}
+
+func APIV2GetLinkSchedules(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksRead); !ok {
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	schedules, status, err := getSchedules(c, urlObj)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    schedules,
+	})
+}
+
+func APIV2CreateLinkSchedule(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksWrite); !ok {
+		return
+	}
+
+	var request CreateScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, ErrCodeInvalidRequest, "Request body must be valid JSON")
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	schedule, status, err := createSchedule(c, urlObj, request)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIV2Response{
+		Success: true,
+		Data:    schedule,
+	})
+}
+
+func APIV2DeleteLinkSchedule(c *gin.Context) {
+	if ok := authenticateAPIRequest(c, models.ScopeLinksWrite); !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithAPIError(c, http.StatusNotFound, ErrCodeNotFound, "Scheduled change does not exist")
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	if status, err := deleteSchedule(c, urlObj, id); err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		abortWithAPIError(c, status, errorCodeFromStatus(status), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+	})
+}
+
+func HandleGetSchedules(c *gin.Context) {
+	urlObj, status, err := lookupOwnedLink(c, models.NormalizeHost(c.PostForm("domain")), c.PostForm("slug"))
+	if err != nil {
+		abortWithScheduleError(c, status, err)
+		return
+	}
+
+	schedules, status, err := getSchedules(c, urlObj)
+	if err != nil {
+		abortWithScheduleError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedules,
+	})
+}
+
+func HandleCreateSchedule(c *gin.Context) {
+	urlObj, status, err := lookupOwnedLink(c, models.NormalizeHost(c.PostForm("domain")), c.PostForm("slug"))
+	if err != nil {
+		abortWithScheduleError(c, status, err)
+		return
+	}
+
+	// 10/31/2018 1:57 PM
+	scheduled, err := time.Parse("01/02/2006 3:04 PM", c.PostForm("scheduled"))
+	if err != nil {
+		abortWithScheduleError(c, http.StatusBadRequest, fmt.Errorf("Invalid scheduled time"))
+		return
+	}
+
+	schedule, status, err := createSchedule(c, urlObj, CreateScheduleRequest{
+		URL:       c.PostForm("url"),
+		Scheduled: scheduled.Unix(),
+	})
+	if err != nil {
+		abortWithScheduleError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+func HandleDeleteSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.PostForm("id"), 10, 64)
+	if err != nil {
+		abortWithScheduleError(c, http.StatusNotFound, fmt.Errorf("Scheduled change does not exist"))
+		return
+	}
+
+	urlObj, status, err := lookupOwnedLink(c, models.NormalizeHost(c.PostForm("domain")), c.PostForm("slug"))
+	if err != nil {
+		abortWithScheduleError(c, status, err)
+		return
+	}
+
+	if status, err := deleteSchedule(c, urlObj, id); err != nil {
+		abortWithScheduleError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func abortWithScheduleError(c *gin.Context, status int, err error) {
+	if status == http.StatusInternalServerError {
+		c.Error(err)
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
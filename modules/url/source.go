@@ -0,0 +1,15 @@
+package url
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+)
+
+// clickSource tells where a click came from. Our own source markers win
+// over the utm parameters and the referrer of the click.
+func clickSource(c *gin.Context) models.ClickSource {
+	if source := c.Query(SourceQuery); models.IsValidClickSource(source) {
+		return models.ClickSource{Source: source, Medium: source}
+	}
+	return models.ParseClickSource(c.GetHeader("Referer"), c.Request.URL.Query(), c.Request.Host)
+}
@@ -0,0 +1,25 @@
+package url
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickSource(t *testing.T) {
+	for path, expected := range map[string]models.ClickSource{
+		"/abc?ta_source=qr":                          {Source: models.ClickSourceQR, Medium: models.MediumQR},
+		"/abc?ta_source=qr&utm_source=newsletter":    {Source: models.ClickSourceQR, Medium: models.MediumQR},
+		"/abc?utm_source=newsletter&utm_campaign=q4": {Source: "newsletter", Medium: models.MediumReferral, Campaign: "q4"},
+		"/abc?ta_source=other":                       {Source: "twitter", Medium: models.MediumSocial},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", path, nil)
+		c.Request.Header.Set("Referer", "https://t.co/xyz")
+		assert.Equal(t, expected, clickSource(c), path)
+	}
+}
@@ -0,0 +1,185 @@
+package url
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/middleware"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/pg"
+	"github.com/jmoiron/sqlx"
+)
+
+var errInvalidInterval = fmt.Errorf("interval must be %v, %v or %v", models.IntervalHour, models.IntervalDay, models.IntervalWeek)
+
+// timeSeriesQuery is the range of a time series. To is excluded.
+type timeSeriesQuery struct {
+	Interval string
+	Location *time.Location
+	From     time.Time
+	To       time.Time
+}
+
+// parseTimeSeriesQuery reads the interval, tz, from and to parameters of a
+// time series request with value. The range defaults to the last 48 hours,
+// 30 days or 12 weeks up to now.
+func parseTimeSeriesQuery(value func(string) string, now time.Time) (*timeSeriesQuery, error) {
+	query := &timeSeriesQuery{
+		Interval: value("interval"),
+		Location: time.UTC,
+		To:       now,
+	}
+	if query.Interval == "" {
+		query.Interval = models.IntervalDay
+	}
+	if !models.IsValidInterval(query.Interval) {
+		return nil, errInvalidInterval
+	}
+	if tz := value("tz"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("Unknown timezone %v", tz)
+		}
+		query.Location = location
+	}
+
+	if to := value("to"); to != "" {
+		i, err := strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("to must be a unix timestamp")
+		}
+		query.To = time.Unix(i, 0)
+	}
+	switch query.Interval {
+	case models.IntervalHour:
+		query.From = query.To.Add(-48 * time.Hour)
+	case models.IntervalWeek:
+		query.From = query.To.AddDate(0, 0, -7*12)
+	default:
+		query.From = query.To.AddDate(0, 0, -30)
+	}
+	if from := value("from"); from != "" {
+		i, err := strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("from must be a unix timestamp")
+		}
+		query.From = time.Unix(i, 0)
+	}
+
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if !models.HasWholeHourOffset(query.Location, query.From) || !models.HasWholeHourOffset(query.Location, query.To) {
+		return nil, fmt.Errorf("Timezone %v is not a whole number of hours from UTC", query.Location)
+	}
+	if models.CountBuckets(query.From, query.To, query.Interval, query.Location) > models.MaxTimeBuckets {
+		return nil, fmt.Errorf("A time series may have at most %v buckets. Choose a shorter range or a longer interval", models.MaxTimeBuckets)
+	}
+	return query, nil
+}
+
+// getTimeSeries returns the clicks of a url in the range of query and in the
+// period before it. Both are added up from the hourly stats in one query.
+func getTimeSeries(db *sqlx.DB, domain, slug string, query *timeSeriesQuery) (*models.TimeSeries, error) {
+	start := models.BucketStart(query.From, query.Interval, query.Location)
+	n := models.CountBuckets(query.From, query.To, query.Interval, query.Location)
+	previousFrom := models.AddBuckets(start, query.Interval, -n)
+
+	stats, err := pg.GetURLHourlyStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+		"from":   previousFrom,
+		"to":     query.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := &models.TimeSeries{
+		Interval:     query.Interval,
+		Timezone:     query.Location.String(),
+		From:         start,
+		To:           query.To,
+		PreviousFrom: previousFrom,
+	}
+	series.Buckets, series.Total = models.FillBuckets(stats, start, query.To, query.Interval, query.Location)
+	series.Previous, series.PreviousTotal = models.FillBuckets(stats, previousFrom, start, query.Interval, query.Location)
+	return series, nil
+}
+
+// HandleGetTimeSeries returns the clicks over time of a short link as JSON,
+// for the chart of the analytics page. Like the rest of the page it only
+// needs the short link.
+func HandleGetTimeSeries(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	domain, slug, ok := parseShortURL(db, c.PostForm("url"))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid URL",
+		})
+		return
+	}
+	if alias, err := pg.GetURLAlias(db, domain, slug); err == nil {
+		slug = alias.Slug
+	}
+
+	query, err := parseTimeSeriesQuery(c.PostForm, time.Now())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	series, err := getTimeSeries(db, domain, slug, query)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    series,
+	})
+}
+
+func APIV2GetLinkTimeSeries(c *gin.Context) {
+	db := middleware.GetDB(c)
+
+	if ok := authenticateAPIRequest(c, models.ScopeStatsRead); !ok {
+		return
+	}
+
+	urlObj, ok := getOwnedLink(c, v2LinkDomain(c), v2LinkSlug(c))
+	if !ok {
+		return
+	}
+
+	query, err := parseTimeSeriesQuery(c.Query, time.Now())
+	if err != nil {
+		abortWithAPIError(c, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	series, err := getTimeSeries(db, urlObj.Domain, urlObj.Slug, query)
+	if err != nil {
+		c.Error(err)
+		abortWithAPIError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIV2Response{
+		Success: true,
+		Data:    series,
+	})
+}
@@ -0,0 +1,45 @@
+package url
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeSeriesQuery(t *testing.T) {
+	now := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	query, err := parseTimeSeriesQuery(url.Values{}.Get, now)
+	assert.Nil(t, err)
+	assert.Equal(t, models.IntervalDay, query.Interval)
+	assert.Equal(t, time.UTC, query.Location)
+	assert.Equal(t, now.AddDate(0, 0, -30), query.From)
+	assert.Equal(t, now, query.To)
+
+	query, err = parseTimeSeriesQuery(url.Values{
+		"interval": {"hour"},
+		"tz":       {"America/New_York"},
+		"from":     {"1541030400"},
+		"to":       {"1541116800"},
+	}.Get, now)
+	assert.Nil(t, err)
+	assert.Equal(t, models.IntervalHour, query.Interval)
+	assert.Equal(t, "America/New_York", query.Location.String())
+	assert.Equal(t, int64(1541030400), query.From.Unix())
+
+	for _, values := range []url.Values{
+		{"interval": {"month"}},
+		{"tz": {"Mars/Olympus"}},
+		{"tz": {"Asia/Kolkata"}},
+		{"tz": {"Australia/Adelaide"}},
+		{"from": {"yesterday"}},
+		{"from": {"1541116800"}, "to": {"1541030400"}},
+		{"interval": {"hour"}, "from": {"1400000000"}},
+	} {
+		_, err := parseTimeSeriesQuery(values.Get, now)
+		assert.NotNil(t, err, values.Encode())
+	}
+}
@@ -18,28 +18,50 @@ import (
 	"github.com/jasontthai/tinyalias/middleware"
 	"github.com/jasontthai/tinyalias/models"
 	"github.com/jasontthai/tinyalias/modules/auth"
+	"github.com/jasontthai/tinyalias/modules/bots"
+	"github.com/jasontthai/tinyalias/modules/domains"
+	"github.com/jasontthai/tinyalias/modules/geo"
 	"github.com/jasontthai/tinyalias/modules/newsapi"
 	"github.com/jasontthai/tinyalias/modules/queue"
+	"github.com/jasontthai/tinyalias/modules/slugs"
 	"github.com/jasontthai/tinyalias/modules/utils"
 	"github.com/jasontthai/tinyalias/pg"
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 )
 
-var secret string
+var (
+	errInvalidForwardQuery = fmt.Errorf("forward_query must be empty, %v or %v", models.ForwardQueryKeep, models.ForwardQueryOverride)
+	errInvalidMaxClicks    = fmt.Errorf("max_clicks must be 0 for no limit or a positive number of clicks")
+	errInvalidRedirectCode = fmt.Errorf("redirect_code must be 301, 302, 307 or 308")
+	errInvalidCacheControl = fmt.Errorf("cache_control must be printable ASCII of at most %v characters", models.MaxCacheControlLength)
+	errInvalidReferrer     = fmt.Errorf("referrer_policy must be empty or a valid Referrer-Policy")
+	errInvalidDescription  = fmt.Errorf("description may be at most %v characters", models.MaxDescriptionLength)
+	errInvalidCard         = fmt.Errorf("card title and description may be at most %v and %v characters and its image must be an http(s) url",
+		models.MaxCardTitleLength, models.MaxCardDescriptionLength)
+	errInvalidAlias = fmt.Errorf("Alias may only contain letters, numbers, '-' and '_'")
+)
+
 var tinyUrlRegexp *regexp.Regexp
+var slugRegexp = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
 
 func init() {
-	// secret in order to use API GET route
-	secret = os.Getenv("SECRET")
 	tinyUrlRegexp = regexp.MustCompile(os.Getenv("BASE_URL") + "(.+)")
 }
 
 const (
 	NotFoundQuery    = "not-found"
 	ExpiredQuery     = "expired"
+	RemovedQuery     = "removed"
 	ThreatQuery      = "threat"
 	SlugQuery        = "slug"
 	XForwardedHeader = "X-Forwarded-For"
+
+	// OnConflictError fails creating a link when its alias is taken,
+	// OnConflictFallback generates a slug instead
+	OnConflictError    = "error"
+	OnConflictFallback = "fallback"
+	MaxSuggestions     = 5
 )
 
 type APIResponse struct {
@@ -52,12 +74,47 @@ type APIResponse struct {
 	Message    string `json:"message,omitempty"`
 }
 
+// AliasConflictError is returned when a requested alias is used by another url
+type AliasConflictError struct {
+	Alias       string
+	Suggestions []string
+}
+
+func (e *AliasConflictError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("Alias %v is already taken", e.Alias)
+	}
+	return fmt.Sprintf("Alias %v is already taken. Try %v", e.Alias, strings.Join(e.Suggestions, ", "))
+}
+
+// suggestions returns the available aliases of a conflict error
+func suggestions(err error) []string {
+	if conflict, ok := err.(*AliasConflictError); ok {
+		return conflict.Suggestions
+	}
+	return nil
+}
+
 func GetHomePage(c *gin.Context) {
 	if strings.Contains(c.Request.Host, "api") {
 		APIGetURLs(c)
 		return
 	}
 
+	// custom domains only serve links
+	domain, err := domains.Resolve(middleware.GetDB(c), c.Request.Host)
+	if err != nil {
+		c.Error(err)
+	}
+	if domain != "" {
+		location := utils.BaseUrl
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusFound, location)
+		return
+	}
+
 	notFoundQuery := c.Query(NotFoundQuery)
 	var error string
 	if notFoundQuery != "" {
@@ -74,6 +131,11 @@ func GetHomePage(c *gin.Context) {
 		error = "The link you entered has expired. Fancy creating one?"
 	}
 
+	removedQuery := c.Query(RemovedQuery)
+	if removedQuery != "" {
+		error = "The link you entered has been removed by its owner. Fancy creating one?"
+	}
+
 	utils.HandleHtmlResponse(c, http.StatusOK, "main.tmpl.html", gin.H{
 		"error": error,
 	})
@@ -86,11 +148,30 @@ func CreateURL(c *gin.Context) {
 	password := c.Query("password")
 	mindful := c.Query("mindful")
 
-	var expirationTime time.Time
-	var err error
+	request := CreateLinkRequest{
+		URL:          url,
+		Alias:        slug,
+		Password:     password,
+		Mindful:      mindful == "true",
+		Domain:       c.Query("domain"),
+		Strategy:     c.Query("strategy"),
+		OnConflict:   c.Query("on_conflict"),
+		ForwardQuery: c.Query("forward_query"),
+		ForwardPath:  c.Query("forward_path") == "true",
+
+		CacheControl:   c.Query("cache_control"),
+		NoIndex:        c.Query("no_index") == "true",
+		ReferrerPolicy: c.Query("referrer_policy"),
+		Description:    c.Query("description"),
+		Card: models.Card{
+			Title:       c.Query("card_title"),
+			Description: c.Query("card_description"),
+			Image:       c.Query("card_image"),
+		},
+	}
 	if expiration != "" {
 		// 10/31/2018 1:57 PM
-		expirationTime, err = time.Parse("01/02/2006 3:04 PM", expiration)
+		expirationTime, err := time.Parse("01/02/2006 3:04 PM", expiration)
 		if err != nil {
 			c.Error(err)
 			utils.HandleHtmlResponse(c, http.StatusInternalServerError, "main.tmpl.html", gin.H{
@@ -98,9 +179,43 @@ func CreateURL(c *gin.Context) {
 			})
 			return
 		}
+		request.Expiration = expirationTime.Unix()
+	}
+	if notBefore := c.Query("not_before"); notBefore != "" {
+		notBeforeTime, err := time.Parse("01/02/2006 3:04 PM", notBefore)
+		if err != nil {
+			utils.HandleHtmlResponse(c, http.StatusBadRequest, "main.tmpl.html", gin.H{
+				"error":    "Invalid launch time",
+				"original": url,
+			})
+			return
+		}
+		request.NotBefore = notBeforeTime.Unix()
+	}
+	if maxClicks := c.Query("max_clicks"); maxClicks != "" {
+		i, err := strconv.Atoi(maxClicks)
+		if err != nil {
+			utils.HandleHtmlResponse(c, http.StatusBadRequest, "main.tmpl.html", gin.H{
+				"error":    "Click limit must be a number",
+				"original": url,
+			})
+			return
+		}
+		request.MaxClicks = i
+	}
+	if redirectCode := c.Query("redirect_code"); redirectCode != "" {
+		i, err := strconv.Atoi(redirectCode)
+		if err != nil {
+			utils.HandleHtmlResponse(c, http.StatusBadRequest, "main.tmpl.html", gin.H{
+				"error":    "Redirect status code must be a number",
+				"original": url,
+			})
+			return
+		}
+		request.RedirectCode = i
 	}
 
-	shortened, status, err := createURL(c, url, slug, password, expirationTime, mindful == "true")
+	urlObj, status, err := createURL(c, request)
 	if err != nil {
 		if status == http.StatusInternalServerError {
 			c.Error(err)
@@ -112,6 +227,10 @@ func CreateURL(c *gin.Context) {
 		return
 	}
 
+	var shortened string
+	if urlObj != nil {
+		shortened = domains.ShortURL(urlObj.Domain, urlObj.Slug)
+	}
 	utils.HandleHtmlResponse(c, http.StatusOK, "main.tmpl.html", gin.H{
 		"url":      shortened,
 		"original": url,
@@ -122,81 +241,237 @@ func Get(c *gin.Context) {
 	db := middleware.GetDB(c)
 	_, qc := middleware.GetQue(c)
 
-	if handled := handleSpecialRoutes(c); handled {
-		return
+	domain, err := domains.Resolve(db, c.Request.Host)
+	if err != nil {
+		c.Error(err)
 	}
 
-	slug := c.Param("slug")
+	// custom domains only serve links
+	if domain == "" {
+		if handled := handleSpecialRoutes(c); handled {
+			return
+		}
+	}
+
+	slug, preview := previewSlug(c)
+	slug, qrCode := qrSlug(slug)
 	log.WithFields(log.Fields{
-		"slug": slug,
+		"slug":   slug,
+		"domain": domain,
 	}).Debug("Got SLUG")
 
-	urlObj, err := pg.GetURL(db, slug)
+	urlObj, err := pg.GetURL(db, domain, slug)
 	if err != nil && err != sql.ErrNoRows {
 		c.Error(err)
 	}
 
 	if urlObj != nil {
+		// nested paths are only forwarded by links that ask for it
+		extraPath := c.Param("path")
+		if extraPath != "" && extraPath != "/" && !urlObj.ForwardPath {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/?%v=%v", NotFoundQuery, slug))
+			return
+		}
 
-		urlObj.Counter += 1
-		// Update from pending to active if link is clicked
-		if urlObj.Status == models.Pending {
-			urlObj.Status = models.Active
+		// links in the trash keep their slug but no longer redirect
+		if urlObj.Deleted.Valid {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/?%v=%v", RemovedQuery, slug))
+			return
 		}
-		err = pg.UpdateURL(db, urlObj)
-		if err != nil {
-			c.Error(err)
+
+		if urlObj.Status == models.Expired || urlObj.IsExhausted() ||
+			(urlObj.Expired.Valid && urlObj.Expired.Time.Before(time.Now())) {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/?%v=%v", ExpiredQuery, slug))
+			return
 		}
 
-		ip := c.ClientIP()
-		if c.GetHeader(XForwardedHeader) != "" {
-			ip = c.GetHeader(XForwardedHeader)
+		// previews neither count as clicks nor hide unsafe links
+		if preview && !qrCode {
+			renderPreview(c, urlObj)
+			return
 		}
-		// Dispatch ParseGeoRequestJob
-		if err := queue.DispatchParseGeoRequestJob(qc, queue.ParseGeoRequest{
-			Slug: slug,
-			IP:   ip,
-		}); err != nil {
-			log.WithFields(log.Fields{
-				"slug": slug,
-				"ip":   ip,
-			}).WithError(err).Error("error sending queue job")
+
+		// return spammed
+		if urlObj.Status != models.Active && urlObj.Status != models.Pending {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/?%v=%v&%v=%v", ThreatQuery, urlObj.Status, SlugQuery, slug))
+			return
 		}
 
-		if urlObj.Status == models.Expired || (urlObj.Expired.Valid && urlObj.Expired.Time.Before(time.Now())) {
-			c.Redirect(http.StatusFound, fmt.Sprintf("?%v=%v", ExpiredQuery, slug))
+		// QR codes are only handed out for links that would redirect
+		if qrCode {
+			renderQRCode(c, urlObj)
 			return
 		}
 
-		// return spammed
-		if urlObj.Status != models.Active {
-			c.Redirect(http.StatusFound, fmt.Sprintf("?%v=%v&%v=%v", ThreatQuery, urlObj.Status, SlugQuery, slug))
+		// chat apps unfurl links with the card of their owner, humans are
+		// redirected as usual
+		if !urlObj.Card.IsEmpty() {
+			c.Header("Vary", "User-Agent")
+		}
+		if wantsCard(c, urlObj) {
+			renderCard(c, urlObj)
 			return
 		}
 
+		// links published ahead of their launch wait without counting clicks
+		if !urlObj.IsLaunched(time.Now()) {
+			utils.HandleHtmlResponse(c, http.StatusOK, "coming_soon.tmpl.html", gin.H{
+				"launch":     urlObj.NotBefore.Time.UTC().Format("Jan 2, 2006 3:04 PM MST"),
+				"launchUnix": urlObj.NotBefore.Time.Unix(),
+			})
+			return
+		}
+
+		// the password is never passed on to the destination
+		query := c.Request.URL.Query()
+		query.Del("password")
+		query.Del(PreviewQuery)
+		query.Del(SourceQuery)
+
 		if urlObj.Password != "" {
 			if c.Query("password") != "" {
 				err = models.VerifyPassword(urlObj.Password, c.Query("password"))
 				if err != nil {
 					utils.HandleHtmlResponse(c, http.StatusOK, "password.tmpl.html", gin.H{
 						"error": "Wrong Password. Try Again.",
+						"query": query,
 					})
 					return
 				}
 			} else {
-				utils.HandleHtmlResponse(c, http.StatusOK, "password.tmpl.html", gin.H{})
+				utils.HandleHtmlResponse(c, http.StatusOK, "password.tmpl.html", gin.H{
+					"query": query,
+				})
 				return
 			}
 		}
 
+		// bots are redirected without using up the clicks of the link
+		bot := bots.Classify(c.Request, geo.ClientIP(c))
+		counted := bot == "" || utils.CountBots
+
+		// only clicks that get through count, and the last click of a
+		// click-limited link goes to a single visitor
+		if counted {
+			clicked, err := pg.ClickURL(db, domain, slug)
+			if err == sql.ErrNoRows {
+				c.Redirect(http.StatusFound, fmt.Sprintf("/?%v=%v", ExpiredQuery, slug))
+				return
+			}
+			if err != nil {
+				c.Error(err)
+				// clicks of limited links cannot be given out without counting them
+				if urlObj.MaxClicks > 0 {
+					c.AbortWithStatus(http.StatusServiceUnavailable)
+					return
+				}
+			} else {
+				// Update from pending to active if link is clicked
+				if urlObj.Status == models.Pending && clicked.Status == models.Active {
+					recordRevision(c, models.RevisionStatus, clicked,
+						models.PropertyMap{"status": models.Pending}, models.PropertyMap{"status": models.Active})
+				}
+				urlObj = clicked
+			}
+		} else if urlObj.Status == models.Pending {
+			// bots activate a link all the same, or it would be removed as
+			// never used
+			activated, err := pg.ActivateURL(db, domain, slug)
+			if err != nil && err != sql.ErrNoRows {
+				c.Error(err)
+			} else if err == nil {
+				recordRevision(c, models.RevisionStatus, activated,
+					models.PropertyMap{"status": models.Pending}, models.PropertyMap{"status": models.Active})
+				urlObj = activated
+			}
+		}
+
+		variant := assignVariant(c, urlObj)
+		source := clickSource(c)
+
+		ip := c.ClientIP()
+		if c.GetHeader(XForwardedHeader) != "" {
+			ip = c.GetHeader(XForwardedHeader)
+		}
+		// Dispatch ParseGeoRequestJob
+		if err := queue.DispatchParseGeoRequestJob(qc, queue.ParseGeoRequest{
+			Domain:   domain,
+			Slug:     slug,
+			IP:       ip,
+			Variant:  variant,
+			Source:   source.Source,
+			Medium:   source.Medium,
+			Campaign: source.Campaign,
+			Bot:      bot,
+			Counted:  counted,
+
+			Clicked:   time.Now(),
+			IPHash:    models.HashIP(geo.ClientIP(c), utils.IPHashSecret),
+			Referrer:  truncate(c.GetHeader("Referer"), models.MaxReferrerLength),
+			UserAgent: truncate(c.GetHeader("User-Agent"), models.MaxUserAgentLength),
+			Language:  models.PrimaryLanguage(c.GetHeader("Accept-Language")),
+		}); err != nil {
+			log.WithFields(log.Fields{
+				"slug": slug,
+				"ip":   ip,
+			}).WithError(err).Error("error sending queue job")
+		}
+
+		// bots did not use up a click, so they never learn where a limited
+		// link goes
+		if !counted && urlObj.MaxClicks > 0 {
+			if !urlObj.Card.IsEmpty() {
+				renderCard(c, urlObj)
+			} else {
+				renderPreview(c, urlObj)
+			}
+			return
+		}
+
+		// responses depend on the platform of the visitor
+		if len(urlObj.Targets) > 0 {
+			c.Header("Vary", "User-Agent")
+		}
+		visitor := models.Visitor{
+			UserAgent: c.GetHeader("User-Agent"),
+			Variant:   variant,
+		}
+		if len(urlObj.GeoTargets) > 0 {
+			location := geo.Lookup(geo.ClientIP(c))
+			visitor.Country, visitor.Region = location.Country, location.Region
+		}
+		destination := urlObj.Destination(visitor, extraPath, query)
+		for key, values := range urlObj.RedirectHeaders() {
+			c.Header(key, values[0])
+		}
 		if urlObj.Mindful {
 			utils.HandleHtmlResponse(c, http.StatusOK, "mindful.tmpl.html", gin.H{
-				"url": urlObj.Url,
+				"url": destination,
 			})
 			return
 		}
 
-		c.Redirect(http.StatusFound, urlObj.Url)
+		c.Redirect(urlObj.RedirectStatus(), destination)
+		return
+	}
+
+	// renamed urls keep redirecting from their former slugs
+	alias, err := pg.GetURLAlias(db, domain, slug)
+	if err != nil && err != sql.ErrNoRows {
+		c.Error(err)
+	}
+	if alias != nil {
+		location := "/" + alias.Slug
+		if preview {
+			location += PreviewSuffix
+		}
+		if path := c.Param("path"); path != "" && path != "/" {
+			location += path
+		}
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusFound, location)
 		return
 	}
 	c.Redirect(http.StatusFound, fmt.Sprintf("/?%v=%v", NotFoundQuery, slug))
@@ -204,6 +479,14 @@ func Get(c *gin.Context) {
 }
 
 func APICreateURL(c *gin.Context) {
+	if status, err := checkAPIKey(c, models.ScopeLinksWrite); err != nil {
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
 	url := c.Query("url")
 	slug := c.Query("alias")
 	password := c.Query("password")
@@ -224,14 +507,73 @@ func APICreateURL(c *gin.Context) {
 		expiration = time.Unix(i, 0)
 	}
 
-	shortened, status, err := createURL(c, url, slug, password, expiration, mindful == "true")
+	request := CreateLinkRequest{
+		URL:          url,
+		Alias:        slug,
+		Password:     password,
+		Mindful:      mindful == "true",
+		Domain:       c.Query("domain"),
+		Strategy:     c.Query("strategy"),
+		OnConflict:   c.Query("on_conflict"),
+		ForwardQuery: c.Query("forward_query"),
+		ForwardPath:  c.Query("forward_path") == "true",
+
+		CacheControl:   c.Query("cache_control"),
+		NoIndex:        c.Query("no_index") == "true",
+		ReferrerPolicy: c.Query("referrer_policy"),
+		Description:    c.Query("description"),
+		Card: models.Card{
+			Title:       c.Query("card_title"),
+			Description: c.Query("card_description"),
+			Image:       c.Query("card_image"),
+		},
+	}
+	if !expiration.Equal(time.Time{}) {
+		request.Expiration = expiration.Unix()
+	}
+	if maxClicks := c.Query("max_clicks"); maxClicks != "" {
+		i, err := strconv.Atoi(maxClicks)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Failed to parse max_clicks. max_clicks must be a number",
+			})
+			return
+		}
+		request.MaxClicks = i
+	}
+	if redirectCode := c.Query("redirect_code"); redirectCode != "" {
+		i, err := strconv.Atoi(redirectCode)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Failed to parse redirect_code. redirect_code must be a number",
+			})
+			return
+		}
+		request.RedirectCode = i
+	}
+	if notBefore := c.Query("not_before"); notBefore != "" {
+		i, err := strconv.ParseInt(notBefore, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Failed to parse not_before. not_before must be unix timestamp",
+			})
+			return
+		}
+		request.NotBefore = i
+	}
+
+	urlObj, status, err := createURL(c, request)
 	if err != nil {
 		if status == http.StatusInternalServerError {
 			c.Error(err)
 		}
 		c.AbortWithStatusJSON(status, gin.H{
-			"success": false,
-			"error":   err.Error(),
+			"success":     false,
+			"error":       err.Error(),
+			"suggestions": suggestions(err),
 		})
 		return
 	}
@@ -239,9 +581,11 @@ func APICreateURL(c *gin.Context) {
 	res := APIResponse{
 		Success:  true,
 		Password: password,
-		Short:    shortened,
 		Original: url,
 	}
+	if urlObj != nil {
+		res.Short = domains.ShortURL(urlObj.Domain, urlObj.Slug)
+	}
 	if !expiration.Equal(time.Time{}) {
 		res.Expiration = expiration.Unix()
 	}
@@ -253,7 +597,15 @@ func APICreateURL(c *gin.Context) {
 func APIGetURLs(c *gin.Context) {
 	db := middleware.GetDB(c)
 
-	if secret != c.Query("secret") {
+	if status, err := checkAPIKey(c, models.ScopeLinksRead); err != nil {
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"success": false,
 		})
@@ -273,6 +625,11 @@ func APIGetURLs(c *gin.Context) {
 	clauses := make(map[string]interface{})
 	clauses["_limit"] = limit
 	clauses["_offset"] = offset
+
+	// allow admin to query for all urls
+	if user.Role != models.RoleAdmin {
+		clauses["username"] = user.Username
+	}
 	urls, err := pg.GetURLs(db, clauses)
 	if err != nil {
 		c.Error(err)
@@ -289,58 +646,108 @@ func APIGetURLs(c *gin.Context) {
 	})
 }
 
-func createURL(c *gin.Context, url, slug, password string, expiration time.Time, mindful bool) (string, int, error) {
+func createURL(c *gin.Context, request CreateLinkRequest) (*models.URL, int, error) {
 	db := middleware.GetDB(c)
 	_, qc := middleware.GetQue(c)
 
-	var shortened string
+	url, slug := request.URL, request.Alias
 	if url == "" {
-		return shortened, http.StatusOK, nil
+		return nil, http.StatusOK, nil
 	}
 
-	if ok := govalidator.IsEmail(url); ok {
-		return "", http.StatusBadRequest, fmt.Errorf("Cannot shorten an email")
+	user := auth.GetAuthenticatedUser(c)
+	domain := models.NormalizeHost(request.Domain)
+	if status, err := domains.Authorize(db, domain, user); err != nil {
+		return nil, status, err
 	}
 
-	if ok := govalidator.IsURL(url); !ok {
-		return "", http.StatusBadRequest, fmt.Errorf("Invalid URL")
+	generator, err := slugs.Get(request.Strategy)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
 	}
-
-	// URL sanitization
-	url = strings.TrimSpace(url)
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "https://" + url
+	if request.OnConflict != "" && request.OnConflict != OnConflictError && request.OnConflict != OnConflictFallback {
+		return nil, http.StatusBadRequest, fmt.Errorf("on_conflict must be %v or %v", OnConflictError, OnConflictFallback)
+	}
+	if !models.IsValidForwardQuery(request.ForwardQuery) {
+		return nil, http.StatusBadRequest, errInvalidForwardQuery
+	}
+	if request.MaxClicks < 0 {
+		return nil, http.StatusBadRequest, errInvalidMaxClicks
+	}
+	if !models.IsValidRedirectCode(request.RedirectCode) {
+		return nil, http.StatusBadRequest, errInvalidRedirectCode
+	}
+	if !models.IsValidCacheControl(request.CacheControl) {
+		return nil, http.StatusBadRequest, errInvalidCacheControl
+	}
+	if !models.IsValidReferrerPolicy(request.ReferrerPolicy) {
+		return nil, http.StatusBadRequest, errInvalidReferrer
+	}
+	request.Description = strings.TrimSpace(request.Description)
+	if len([]rune(request.Description)) > models.MaxDescriptionLength {
+		return nil, http.StatusBadRequest, errInvalidDescription
 	}
 
-	parseURL, err := url2.Parse(url)
+	url, status, err := sanitizeURL(c, url)
 	if err != nil {
-		c.Error(err)
-		return "", http.StatusBadRequest, fmt.Errorf("Invalid URL")
+		return nil, status, err
 	}
-	if parseURL != nil {
-		domain, err := pg.GetDomain(db, strings.ToLower(parseURL.Hostname()))
-		if err != nil && err != sql.ErrNoRows {
-			c.Error(err)
+	targets, status, err := sanitizeTargets(c, request.Targets)
+	if err != nil {
+		return nil, status, err
+	}
+	geoTargets, status, err := sanitizeGeoTargets(c, request.GeoTargets)
+	if err != nil {
+		return nil, status, err
+	}
+	variants, status, err := sanitizeVariants(c, request.Variants)
+	if err != nil {
+		return nil, status, err
+	}
+	card, status, err := sanitizeCard(request.Card)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if slug != "" {
+		// other characters would clash with the preview and QR code routes
+		if !slugRegexp.MatchString(slug) {
+			return nil, http.StatusBadRequest, errInvalidAlias
 		}
-		if domain != nil && domain.Blacklist {
-			return "", http.StatusBadRequest, fmt.Errorf("You are blacklisted.")
+		if status, err := checkReserved(db, slug); err != nil {
+			return nil, status, err
 		}
 	}
 
-	urlObj, err := pg.GetURL(db, slug)
+	urlObj, err := pg.GetURL(db, domain, slug)
 	if err != nil && err != sql.ErrNoRows {
-		return "", http.StatusInternalServerError, err
+		return nil, http.StatusInternalServerError, err
 	}
 	if urlObj != nil {
-		if urlObj.Url == url {
-			return utils.BaseUrl + urlObj.Slug, http.StatusOK, nil
+		var username string
+		if user != nil {
+			username = user.Username
+		}
+		if isSameLink(urlObj, url, username) {
+			return urlObj, http.StatusOK, nil
+		}
+		if status, err := aliasConflict(db, domain, slug, request.OnConflict); err != nil {
+			return nil, status, err
 		}
 		// url already exists with this slug, generate a new slug
 		slug = ""
-	}
-
-	if slug == "" {
-		slug = models.GenerateSlug(6)
+	} else if slug != "" {
+		// former slugs of renamed urls are taken as well
+		alias, err := pg.GetURLAlias(db, domain, slug)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, http.StatusInternalServerError, err
+		}
+		if alias != nil {
+			if status, err := aliasConflict(db, domain, slug, request.OnConflict); err != nil {
+				return nil, status, err
+			}
+			slug = ""
+		}
 	}
 
 	ip := c.ClientIP()
@@ -350,52 +757,534 @@ func createURL(c *gin.Context, url, slug, password string, expiration time.Time,
 
 	urlObj = &models.URL{
 		Url:     url,
-		Slug:    slug,
+		Domain:  domain,
 		Created: time.Now(),
 		IP:      ip,
-		Mindful: mindful,
+		Mindful: request.Mindful,
+
+		MaxClicks:    request.MaxClicks,
+		ForwardQuery: request.ForwardQuery,
+		ForwardPath:  request.ForwardPath,
+		Targets:      targets,
+		GeoTargets:   geoTargets,
+		Variants:     variants,
+
+		RedirectCode:   request.RedirectCode,
+		CacheControl:   strings.TrimSpace(request.CacheControl),
+		NoIndex:        request.NoIndex,
+		ReferrerPolicy: request.ReferrerPolicy,
+		Description:    request.Description,
+		Card:           card,
 	}
 
-	if password != "" {
-		urlObj.Password, err = models.TransformPassword(password)
+	if request.Password != "" {
+		urlObj.Password, err = models.TransformPassword(request.Password)
 		if err != nil {
-			return "", http.StatusInternalServerError, err
+			return nil, http.StatusInternalServerError, err
 		}
 	}
-	if !expiration.Equal(time.Time{}) {
-		urlObj.Expired = null.TimeFrom(expiration)
+	if request.Expiration != 0 {
+		urlObj.Expired = null.TimeFrom(time.Unix(request.Expiration, 0))
+	}
+	if request.NotBefore != 0 {
+		urlObj.NotBefore = null.TimeFrom(time.Unix(request.NotBefore, 0))
 	}
 
-	user := auth.GetAuthenticatedUser(c)
 	if user != nil {
 		urlObj.Username = user.Username
 	}
 
-	// Run spam job on new link
-	err = pg.CreateURL(db, urlObj)
-	if err != nil {
-		return "", http.StatusInternalServerError, err
+	// Another request may take the same slug between the lookup and the
+	// insert, so collisions are retried with a freshly generated slug.
+	for attempt := 0; ; attempt++ {
+		if attempt == slugs.MaxAttempts {
+			return nil, http.StatusInternalServerError, fmt.Errorf("Failed to generate a unique slug after %v attempts", attempt)
+		}
+		if slug == "" {
+			slug, err = generator.Generate(db, url, attempt)
+			if err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			existing, available, err := slugAvailable(db, domain, slug)
+			if err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			if existing != nil && isSameLink(existing, url, urlObj.Username) {
+				// deterministic strategies map the same url to the same link
+				return existing, http.StatusOK, nil
+			}
+			if !available {
+				slug = ""
+				continue
+			}
+		}
+
+		urlObj.Slug = slug
+		err = pg.CreateURL(db, urlObj)
+		if err == nil {
+			break
+		}
+		if !pg.IsUniqueViolation(err) {
+			return nil, http.StatusInternalServerError, err
+		}
+		if slug == request.Alias {
+			if status, err := aliasConflict(db, domain, slug, request.OnConflict); err != nil {
+				return nil, status, err
+			}
+		}
+		slug = ""
 	}
-	shortened = utils.BaseUrl + urlObj.Slug
 
-	// Dispatch ParseGeoRequestJob
+	recordRevision(c, models.RevisionCreate, urlObj, nil, urlObj.RevisionValues())
+	shortened := domains.ShortURL(urlObj.Domain, urlObj.Slug)
+
+	// Run spam job on new link
 	if err := queue.DispatchDetectSpamJob(qc, url); err != nil {
 		log.WithFields(log.Fields{
 			"url": url,
 		}).WithError(err).Error("error sending spam detect job")
 	}
+	if err := queue.DispatchFetchMetadataJob(qc, urlObj.Domain, urlObj.Slug); err != nil {
+		log.WithFields(log.Fields{
+			"slug": urlObj.Slug,
+		}).WithError(err).Error("error sending fetch metadata job")
+	}
 
 	log.WithFields(log.Fields{
 		"short":    shortened,
 		"original": url,
 	}).Info("Shortened URL generated")
-	return shortened, http.StatusOK, nil
+	return urlObj, http.StatusOK, nil
+}
+
+// slugAvailable reports whether a generated slug is neither a link, a
+// former slug of a renamed link nor reserved on a domain. The link using the
+// slug is returned too.
+func slugAvailable(db *sqlx.DB, domain, slug string) (*models.URL, bool, error) {
+	reserved, err := slugs.Reserved(db, slug)
+	if err != nil {
+		return nil, false, err
+	}
+	if reserved != nil {
+		return nil, false, nil
+	}
+	urlObj, err := pg.GetURL(db, domain, slug)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+	if urlObj != nil {
+		return urlObj, false, nil
+	}
+	alias, err := pg.GetURLAlias(db, domain, slug)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+	return nil, alias == nil, nil
+}
+
+// aliasConflict returns a conflict with available suggestions for a taken
+// alias unless the request opted in to falling back to a generated slug
+func aliasConflict(db *sqlx.DB, domain, slug, mode string) (int, error) {
+	if mode == OnConflictFallback {
+		return http.StatusOK, nil
+	}
+
+	var suggestions []string
+	for _, variant := range slugs.Variants(slug) {
+		if len(suggestions) == MaxSuggestions {
+			break
+		}
+		_, available, err := slugAvailable(db, domain, variant)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if available {
+			suggestions = append(suggestions, variant)
+		}
+	}
+	return http.StatusConflict, &AliasConflictError{
+		Alias:       slug,
+		Suggestions: suggestions,
+	}
+}
+
+// checkReserved returns a conflict if slug cannot be used as an alias
+func checkReserved(db *sqlx.DB, slug string) (int, error) {
+	reserved, err := slugs.Reserved(db, slug)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if reserved != nil {
+		return http.StatusConflict, fmt.Errorf("Alias %v is reserved. Please choose another alias", slug)
+	}
+	return http.StatusOK, nil
+}
+
+// sanitizeURL validates a destination url, adds a scheme when it is missing
+// and rejects blacklisted domains.
+func sanitizeURL(c *gin.Context, url string) (string, int, error) {
+	db := middleware.GetDB(c)
+
+	if ok := govalidator.IsEmail(url); ok {
+		return "", http.StatusBadRequest, fmt.Errorf("Cannot shorten an email")
+	}
+
+	if ok := govalidator.IsURL(url); !ok {
+		return "", http.StatusBadRequest, fmt.Errorf("Invalid URL")
+	}
+
+	// URL sanitization
+	url = strings.TrimSpace(url)
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "https://" + url
+	}
+
+	parseURL, err := url2.Parse(url)
+	if err != nil {
+		c.Error(err)
+		return "", http.StatusBadRequest, fmt.Errorf("Invalid URL")
+	}
+	if parseURL != nil {
+		domain, err := pg.GetDomain(db, strings.ToLower(parseURL.Hostname()))
+		if err != nil && err != sql.ErrNoRows {
+			c.Error(err)
+		}
+		if domain != nil && domain.Blacklist {
+			return "", http.StatusBadRequest, fmt.Errorf("You are blacklisted.")
+		}
+	}
+	return url, http.StatusOK, nil
+}
+
+// sanitizeTargets validates the targeting rules of a url and sanitizes their
+// destinations like the url itself
+func sanitizeTargets(c *gin.Context, targets models.TargetRules) (models.TargetRules, int, error) {
+	if len(targets) > models.MaxTargetRules {
+		return nil, http.StatusBadRequest, fmt.Errorf("A link may have at most %v targeting rules", models.MaxTargetRules)
+	}
+
+	sanitized := make(models.TargetRules, 0, len(targets))
+	for _, rule := range targets {
+		if !models.IsValidPlatform(rule.Platform) {
+			return nil, http.StatusBadRequest, fmt.Errorf("Unknown platform %v. Use one of: %v",
+				rule.Platform, strings.Join(models.Platforms(), ", "))
+		}
+		url, status, err := sanitizeURL(c, rule.URL)
+		if err != nil {
+			return nil, status, fmt.Errorf("%v target: %v", rule.Platform, err.Error())
+		}
+		sanitized = append(sanitized, models.TargetRule{
+			Platform: rule.Platform,
+			URL:      url,
+		})
+	}
+	return sanitized, http.StatusOK, nil
+}
+
+// sanitizeGeoTargets validates the geo rules of a url and sanitizes their
+// destinations like the url itself
+func sanitizeGeoTargets(c *gin.Context, geoTargets models.GeoRules) (models.GeoRules, int, error) {
+	if len(geoTargets) > models.MaxTargetRules {
+		return nil, http.StatusBadRequest, fmt.Errorf("A link may have at most %v geo rules", models.MaxTargetRules)
+	}
+
+	sanitized := make(models.GeoRules, 0, len(geoTargets))
+	for _, rule := range geoTargets {
+		rule = rule.Normalize()
+		if !rule.IsValid() {
+			return nil, http.StatusBadRequest, fmt.Errorf("Invalid country %v or region %v. Use ISO codes, e.g. DE or US and CA", rule.Country, rule.Region)
+		}
+		url, status, err := sanitizeURL(c, rule.URL)
+		if err != nil {
+			return nil, status, fmt.Errorf("%v target: %v", rule.Country, err.Error())
+		}
+		rule.URL = url
+		sanitized = append(sanitized, rule)
+	}
+	return sanitized, http.StatusOK, nil
+}
+
+// sanitizeVariants validates the variants of an A/B link and sanitizes their
+// destinations like the url itself. Variants without a name are named by
+// their position: A, B, C...
+func sanitizeVariants(c *gin.Context, variants models.Variants) (models.Variants, int, error) {
+	if len(variants) > models.MaxVariants {
+		return nil, http.StatusBadRequest, fmt.Errorf("A link may have at most %v variants", models.MaxVariants)
+	}
+
+	sanitized := make(models.Variants, 0, len(variants))
+	for i, variant := range variants {
+		variant.Name = strings.TrimSpace(variant.Name)
+		if variant.Name == "" {
+			variant.Name = string(rune('A' + i))
+		}
+		if !variant.IsValid() {
+			return nil, http.StatusBadRequest, fmt.Errorf("Invalid variant %v. Names may only contain letters, numbers, '-' and '_' and weights must be between 1 and %v", variant.Name, models.MaxVariantWeight)
+		}
+		if sanitized.Find(variant.Name) != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("Duplicate variant %v", variant.Name)
+		}
+		url, status, err := sanitizeURL(c, variant.URL)
+		if err != nil {
+			return nil, status, fmt.Errorf("Variant %v: %v", variant.Name, err.Error())
+		}
+		variant.URL = url
+		sanitized = append(sanitized, variant)
+	}
+	return sanitized, http.StatusOK, nil
+}
+
+// truncate cuts a header to at most max bytes without splitting a character
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return strings.ToValidUTF8(s[:max], "")
+}
+
+// updateLink applies the changes of request to urlObj. A new slug keeps the old
+// one as an alias redirecting to the url and a new destination is scanned for
+// spam again.
+func updateLink(c *gin.Context, urlObj *models.URL, request UpdateLinkRequest) (int, error) {
+	db := middleware.GetDB(c)
+	_, qc := middleware.GetQue(c)
+
+	if urlObj.Deleted.Valid {
+		return http.StatusConflict, fmt.Errorf("Link is in the trash. Restore it first.")
+	}
+
+	var err error
+	var destinationChanged, urlChanged bool
+	oldValues := urlObj.RevisionValues()
+	if request.URL != nil {
+		url, status, err := sanitizeURL(c, *request.URL)
+		if err != nil {
+			return status, err
+		}
+		urlChanged = url != urlObj.Url
+		destinationChanged = urlChanged
+		urlObj.Url = url
+	}
+
+	var newSlug string
+	if request.Alias != nil && *request.Alias != urlObj.Slug {
+		newSlug = *request.Alias
+		if !slugRegexp.MatchString(newSlug) {
+			return http.StatusBadRequest, errInvalidAlias
+		}
+		if status, err := checkReserved(db, newSlug); err != nil {
+			return status, err
+		}
+		taken, err := pg.GetURL(db, urlObj.Domain, newSlug)
+		if err != nil && err != sql.ErrNoRows {
+			return http.StatusInternalServerError, err
+		}
+		alias, err := pg.GetURLAlias(db, urlObj.Domain, newSlug)
+		if err != nil && err != sql.ErrNoRows {
+			return http.StatusInternalServerError, err
+		}
+		if taken != nil || (alias != nil && alias.Slug != urlObj.Slug) {
+			return http.StatusConflict, fmt.Errorf("Alias %v is already taken", newSlug)
+		}
+	}
+
+	if request.Password != nil {
+		urlObj.Password = ""
+		if *request.Password != "" {
+			urlObj.Password, err = models.TransformPassword(*request.Password)
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+	}
+	if request.Expiration != nil {
+		urlObj.Expired = null.Time{}
+		if *request.Expiration != 0 {
+			urlObj.Expired = null.TimeFrom(time.Unix(*request.Expiration, 0))
+		}
+		// a later expiration brings back an expired link
+		if urlObj.Status == models.Expired && (!urlObj.Expired.Valid || urlObj.Expired.Time.After(time.Now())) {
+			urlObj.Status = models.Active
+		}
+	}
+	if request.Mindful != nil {
+		urlObj.Mindful = *request.Mindful
+	}
+	if request.NotBefore != nil {
+		urlObj.NotBefore = null.Time{}
+		if *request.NotBefore != 0 {
+			urlObj.NotBefore = null.TimeFrom(time.Unix(*request.NotBefore, 0))
+		}
+	}
+	if request.MaxClicks != nil {
+		if *request.MaxClicks < 0 {
+			return http.StatusBadRequest, errInvalidMaxClicks
+		}
+		urlObj.MaxClicks = *request.MaxClicks
+	}
+	if request.ForwardQuery != nil {
+		if !models.IsValidForwardQuery(*request.ForwardQuery) {
+			return http.StatusBadRequest, errInvalidForwardQuery
+		}
+		urlObj.ForwardQuery = *request.ForwardQuery
+	}
+	if request.ForwardPath != nil {
+		urlObj.ForwardPath = *request.ForwardPath
+	}
+	if request.RedirectCode != nil {
+		if !models.IsValidRedirectCode(*request.RedirectCode) {
+			return http.StatusBadRequest, errInvalidRedirectCode
+		}
+		urlObj.RedirectCode = *request.RedirectCode
+	}
+	if request.CacheControl != nil {
+		if !models.IsValidCacheControl(*request.CacheControl) {
+			return http.StatusBadRequest, errInvalidCacheControl
+		}
+		urlObj.CacheControl = strings.TrimSpace(*request.CacheControl)
+	}
+	if request.NoIndex != nil {
+		urlObj.NoIndex = *request.NoIndex
+	}
+	if request.ReferrerPolicy != nil {
+		if !models.IsValidReferrerPolicy(*request.ReferrerPolicy) {
+			return http.StatusBadRequest, errInvalidReferrer
+		}
+		urlObj.ReferrerPolicy = *request.ReferrerPolicy
+	}
+	if request.Description != nil {
+		description := strings.TrimSpace(*request.Description)
+		if len([]rune(description)) > models.MaxDescriptionLength {
+			return http.StatusBadRequest, errInvalidDescription
+		}
+		urlObj.Description = description
+	}
+	if request.Card != nil {
+		card, status, err := sanitizeCard(*request.Card)
+		if err != nil {
+			return status, err
+		}
+		urlObj.Card = card
+	}
+	if request.Targets != nil {
+		targets, status, err := sanitizeTargets(c, *request.Targets)
+		if err != nil {
+			return status, err
+		}
+		if targets.String() != urlObj.Targets.String() {
+			destinationChanged = true
+		}
+		urlObj.Targets = targets
+	}
+	if request.GeoTargets != nil {
+		geoTargets, status, err := sanitizeGeoTargets(c, *request.GeoTargets)
+		if err != nil {
+			return status, err
+		}
+		if geoTargets.String() != urlObj.GeoTargets.String() {
+			destinationChanged = true
+		}
+		urlObj.GeoTargets = geoTargets
+	}
+	if request.Variants != nil {
+		variants, status, err := sanitizeVariants(c, *request.Variants)
+		if err != nil {
+			return status, err
+		}
+		if variants.String() != urlObj.Variants.String() {
+			destinationChanged = true
+		}
+		urlObj.Variants = variants
+	}
+
+	// a new destination gets a fresh spam scan
+	if destinationChanged && urlObj.Status != models.Pending && urlObj.Status != models.Expired {
+		urlObj.Status = models.Active
+	}
+
+	if newSlug == "" {
+		if err = pg.EditURL(db, urlObj); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	} else {
+		if err = pg.EditAndRenameURL(db, urlObj, newSlug); err != nil {
+			// the alias was taken after it was checked
+			if pg.IsUniqueViolation(err) {
+				return http.StatusConflict, fmt.Errorf("Alias %v is already taken", newSlug)
+			}
+			return http.StatusInternalServerError, err
+		}
+		log.WithField("slug", newSlug).WithField("alias", urlObj.Slug).Info("Renamed URL")
+		urlObj.Slug = newSlug
+	}
+
+	if oldValues, newValues := models.DiffRevisionValues(oldValues, urlObj.RevisionValues()); len(newValues) > 0 {
+		recordRevision(c, models.RevisionUpdate, urlObj, oldValues, newValues)
+	}
+
+	if destinationChanged {
+		if err := queue.DispatchDetectSpamJob(qc, urlObj.Url); err != nil {
+			log.WithFields(log.Fields{
+				"url": urlObj.Url,
+			}).WithError(err).Error("error sending spam detect job")
+		}
+	}
+	if urlChanged {
+		if err := queue.DispatchFetchMetadataJob(qc, urlObj.Domain, urlObj.Slug); err != nil {
+			log.WithFields(log.Fields{
+				"slug": urlObj.Slug,
+			}).WithError(err).Error("error sending fetch metadata job")
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// trashLink moves a url to the trash where its owner can restore it until it
+// is purged after utils.TrashRetention
+func trashLink(c *gin.Context, urlObj *models.URL) (int, error) {
+	db := middleware.GetDB(c)
+
+	if urlObj.Deleted.Valid {
+		return http.StatusOK, nil
+	}
+	if err := pg.TrashURL(db, urlObj.Domain, urlObj.Slug); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	urlObj.Deleted = null.TimeFrom(time.Now())
+	recordRevision(c, models.RevisionDelete, urlObj, urlObj.RevisionValues(), nil)
+
+	log.WithField("slug", urlObj.Slug).Info("Deleted URL")
+	return http.StatusOK, nil
+}
+
+func restoreLink(c *gin.Context, urlObj *models.URL) (int, error) {
+	db := middleware.GetDB(c)
+
+	if !urlObj.Deleted.Valid {
+		return http.StatusConflict, fmt.Errorf("Link is not in the trash")
+	}
+	if !urlObj.IsRestorable(utils.TrashRetention) {
+		return http.StatusGone, fmt.Errorf("Link can no longer be restored")
+	}
+	if err := pg.RestoreURL(db, urlObj.Domain, urlObj.Slug); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	urlObj.Deleted = null.Time{}
+	recordRevision(c, models.RevisionRestore, urlObj, nil, urlObj.RevisionValues())
+
+	log.WithField("slug", urlObj.Slug).Info("Restored URL")
+	return http.StatusOK, nil
 }
 
 func handleSpecialRoutes(c *gin.Context) bool {
 	slug := c.Param("slug")
 	var handled bool = true
 
+	if slug == "v2" {
+		handleV2Routes(c)
+		return true
+	}
+
 	hostname := strings.Split(c.Request.Host, ".")
 
 	if hostname[0] == "api" {
@@ -451,7 +1340,7 @@ func handleSpecialRoutes(c *gin.Context) bool {
 func HandleDeleteLinks(c *gin.Context) {
 	db := middleware.GetDB(c)
 	slug := c.PostForm("slug")
-	urlStr := c.PostForm("url")
+	domain := models.NormalizeHost(c.PostForm("domain"))
 
 	user := auth.GetAuthenticatedUser(c)
 	if user == nil {
@@ -461,7 +1350,7 @@ func HandleDeleteLinks(c *gin.Context) {
 		return
 	}
 
-	url, err := pg.GetURL(db, slug)
+	url, err := pg.GetURL(db, domain, slug)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.AbortWithStatusJSON(http.StatusOK, gin.H{
@@ -483,21 +1372,273 @@ func HandleDeleteLinks(c *gin.Context) {
 		return
 	}
 
-	err = pg.DeleteURL(db, urlStr, slug)
+	status, err := trashLink(c, url)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func HandleRestoreLink(c *gin.Context) {
+	db := middleware.GetDB(c)
+	slug := c.PostForm("slug")
+	domain := models.NormalizeHost(c.PostForm("domain"))
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	url, err := pg.GetURL(db, domain, slug)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"success": false,
+			})
+			return
+		}
+		c.Error(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   err,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if user.Role != models.RoleAdmin && url.Username != user.Username {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	status, err := restoreLink(c, url)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func HandleEditLink(c *gin.Context) {
+	db := middleware.GetDB(c)
+	slug := c.PostForm("slug")
+	domain := models.NormalizeHost(c.PostForm("domain"))
+
+	user := auth.GetAuthenticatedUser(c)
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	url, err := pg.GetURL(db, domain, slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"success": false,
+			})
+			return
+		}
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if user.Role != models.RoleAdmin && url.Username != user.Username {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+		})
+		return
+	}
+
+	destination := c.PostForm("url")
+	alias := c.PostForm("alias")
+	mindful := c.PostForm("mindful") == "true"
+	var maxClicks int
+	if maxClicksStr := c.PostForm("max_clicks"); maxClicksStr != "" {
+		var err error
+		if maxClicks, err = strconv.Atoi(maxClicksStr); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid click limit",
+			})
+			return
+		}
+	}
+	var redirectCode int
+	if redirectCodeStr := c.PostForm("redirect_code"); redirectCodeStr != "" {
+		var err error
+		if redirectCode, err = strconv.Atoi(redirectCodeStr); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid redirect status code",
+			})
+			return
+		}
+	}
+	cacheControl := c.PostForm("cache_control")
+	noIndex := c.PostForm("no_index") == "true"
+	referrerPolicy := c.PostForm("referrer_policy")
+	description := c.PostForm("description")
+	card := models.Card{
+		Title:       c.PostForm("card_title"),
+		Description: c.PostForm("card_description"),
+		Image:       c.PostForm("card_image"),
+	}
+	forwardQuery := c.PostForm("forward_query")
+	forwardPath := c.PostForm("forward_path") == "true"
+	// rows without a destination are left out of the targeting rules
+	targets := models.TargetRules{}
+	platforms := c.PostFormArray("target_platform")
+	for i, targetURL := range c.PostFormArray("target_url") {
+		if i < len(platforms) && strings.TrimSpace(targetURL) != "" {
+			targets = append(targets, models.TargetRule{
+				Platform: platforms[i],
+				URL:      targetURL,
+			})
+		}
+	}
+	geoTargets := models.GeoRules{}
+	countries := c.PostFormArray("geo_country")
+	regions := c.PostFormArray("geo_region")
+	for i, targetURL := range c.PostFormArray("geo_url") {
+		if i < len(countries) && i < len(regions) && strings.TrimSpace(targetURL) != "" {
+			geoTargets = append(geoTargets, models.GeoRule{
+				Country: countries[i],
+				Region:  regions[i],
+				URL:     targetURL,
+			})
+		}
+	}
+	variants := models.Variants{}
+	names := c.PostFormArray("variant_name")
+	weights := c.PostFormArray("variant_weight")
+	for i, variantURL := range c.PostFormArray("variant_url") {
+		if i < len(names) && i < len(weights) && strings.TrimSpace(variantURL) != "" {
+			weight, err := strconv.Atoi(weights[i])
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"error":   "Invalid variant weight",
+				})
+				return
+			}
+			variants = append(variants, models.Variant{
+				Name:   names[i],
+				URL:    variantURL,
+				Weight: weight,
+			})
+		}
+	}
+	request := UpdateLinkRequest{
+		URL:          &destination,
+		Mindful:      &mindful,
+		MaxClicks:    &maxClicks,
+		ForwardQuery: &forwardQuery,
+		ForwardPath:  &forwardPath,
+		Targets:      &targets,
+		GeoTargets:   &geoTargets,
+		Variants:     &variants,
+
+		RedirectCode:   &redirectCode,
+		CacheControl:   &cacheControl,
+		NoIndex:        &noIndex,
+		ReferrerPolicy: &referrerPolicy,
+		Description:    &description,
+		Card:           &card,
+	}
+
+	// an empty alias keeps the current one
+	if alias != "" {
+		request.Alias = &alias
+	}
+
+	// an empty password keeps the current one unless asked to remove it
+	password := c.PostForm("password")
+	if password != "" || c.PostForm("remove_password") == "true" {
+		request.Password = &password
+	}
+
+	var expiration int64
+	if expirationStr := c.PostForm("expiration"); expirationStr != "" {
+		// 10/31/2018 1:57 PM
+		expirationTime, err := time.Parse("01/02/2006 3:04 PM", expirationStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid expiration",
+			})
+			return
+		}
+		expiration = expirationTime.Unix()
+	}
+	// saving other fields leaves the expiration and status alone
+	var current int64
+	if url.Expired.Valid {
+		current = url.Expired.Time.Unix()
+	}
+	if expiration != current {
+		request.Expiration = &expiration
+	}
+
+	var notBefore int64
+	if notBeforeStr := c.PostForm("not_before"); notBeforeStr != "" {
+		notBeforeTime, err := time.Parse("01/02/2006 3:04 PM", notBeforeStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid launch time",
+			})
+			return
+		}
+		notBefore = notBeforeTime.Unix()
+	}
+	request.NotBefore = &notBefore
+
+	status, err := updateLink(c, url, request)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.Error(err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	log.WithField("slug", slug).
-		WithField("url", urlStr).
-		Info("Deleted URL")
+	log.WithField("slug", url.Slug).Info("Updated URL")
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
+		"data":    url,
 	})
 }
 
@@ -563,6 +1704,10 @@ func HandleGetLinks(c *gin.Context) {
 		clauses["username"] = user.Username
 		countClauses["username"] = user.Username
 	}
+	if c.PostForm("trash") == "true" {
+		clauses["deleted"] = true
+		countClauses["deleted"] = true
+	}
 	urls, err := pg.GetURLs(db, clauses)
 	if err != nil {
 		c.Error(err)
@@ -606,20 +1751,19 @@ func HandleGetLinks(c *gin.Context) {
 }
 
 func HandleCopySignal(c *gin.Context) {
+	db := middleware.GetDB(c)
 	url := c.PostForm("copy")
 
-	submatches := tinyUrlRegexp.FindStringSubmatch(url)
-	if len(submatches) < 2 {
+	domain, slug, ok := parseShortURL(db, url)
+	if !ok {
 		log.WithField("url", url).Error("Unable to parse slug")
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 			"success": false,
 		})
 		return
 	}
-	slug := submatches[1]
 
-	db := middleware.GetDB(c)
-	urlObj, err := pg.GetURL(db, slug)
+	urlObj, err := pg.GetURL(db, domain, slug)
 	if err != nil {
 		c.Error(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
@@ -630,6 +1774,7 @@ func HandleCopySignal(c *gin.Context) {
 	}
 
 	// Set status to active if copied
+	oldStatus := urlObj.Status
 	urlObj.Status = models.Active
 
 	err = pg.UpdateURL(db, urlObj)
@@ -641,6 +1786,10 @@ func HandleCopySignal(c *gin.Context) {
 		})
 		return
 	}
+	if oldStatus != urlObj.Status {
+		recordRevision(c, models.RevisionStatus, urlObj,
+			models.PropertyMap{"status": oldStatus}, models.PropertyMap{"status": urlObj.Status})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -682,18 +1831,18 @@ func GetAnalytics(c *gin.Context) {
 		}
 	}
 
-	submatches := tinyUrlRegexp.FindStringSubmatch(c.Query("url"))
-	if len(submatches) < 2 {
+	domain, slug, ok := parseShortURL(db, c.Query("url"))
+	if !ok {
 		utils.HandleHtmlResponse(c, http.StatusOK, "analytics.tmpl.html", gin.H{
 			"count": count,
 		})
 		return
 	}
-	slug := submatches[1]
+	if alias, err := pg.GetURLAlias(db, domain, slug); err == nil {
+		slug = alias.Slug
+	}
 
-	stats, err := pg.GetURLStats(db, map[string]interface{}{
-		"slug": slug,
-	})
+	clicks, analytics, err := getAnalytics(db, domain, slug)
 	if err != nil {
 		c.Error(err)
 		utils.HandleHtmlResponse(c, http.StatusOK, "analytics.tmpl.html", gin.H{
@@ -703,32 +1852,188 @@ func GetAnalytics(c *gin.Context) {
 		return
 	}
 
-	var clicks int
-	analytics := make([]models.Analytics, 0)
-
-	for _, stat := range stats {
-		clicks += stat.Counter
-		analytics = append(analytics, models.Analytics{
-			Country: stat.Country,
-			State:   stat.State,
-			Count:   stat.Counter,
-		})
+	variants, err := getVariantAnalytics(db, domain, slug)
+	if err != nil {
+		c.Error(err)
+	}
+	sources, err := getSourceAnalytics(db, domain, slug)
+	if err != nil {
+		c.Error(err)
+	}
+	campaigns, err := getCampaignAnalytics(db, domain, slug)
+	if err != nil {
+		c.Error(err)
+	}
+	devices, err := getDeviceAnalytics(db, domain, slug)
+	if err != nil {
+		c.Error(err)
+	}
+	botHits, err := getBotAnalytics(db, domain, slug)
+	if err != nil {
+		c.Error(err)
 	}
-
-	// sort in descending order of count
-	sort.Slice(analytics, func(i, j int) bool { return analytics[i].Count > analytics[j].Count })
 
 	log.WithFields(log.Fields{
 		"url":       c.Query("url"),
 		"clicks":    clicks,
 		"analytics": analytics,
+		"variants":  variants,
+		"sources":   sources,
+		"campaigns": campaigns,
+		"devices":   devices,
+		"bots":      botHits,
 	}).Info("Returned values")
 
 	utils.HandleHtmlResponse(c, http.StatusOK, "analytics.tmpl.html", gin.H{
 		"url":       c.Query("url"),
 		"clicks":    clicks,
 		"analytics": analytics,
+		"variants":  variants,
+		"sources":   sources,
+		"campaigns": campaigns,
+		"devices":   devices,
+		"bots":      botHits,
 		"count":     count,
 	})
 	return
 }
+
+// isSameLink tells whether an existing link can be given back for a new link
+// of url. Links of others and links in the trash are never handed out.
+func isSameLink(existing *models.URL, url, username string) bool {
+	return existing.Url == url && existing.Username == username && !existing.Deleted.Valid
+}
+
+// parseShortURL returns the domain and slug of a short link on the default
+// domain or on a verified custom domain
+func parseShortURL(db *sqlx.DB, short string) (string, string, bool) {
+	if submatches := tinyUrlRegexp.FindStringSubmatch(short); len(submatches) >= 2 {
+		return "", submatches[1], true
+	}
+
+	if !strings.Contains(short, "://") {
+		short = "https://" + short
+	}
+	parsed, err := url2.Parse(short)
+	if err != nil {
+		return "", "", false
+	}
+	domain, err := domains.Resolve(db, parsed.Host)
+	if err != nil || domain == "" {
+		return "", "", false
+	}
+	slug := strings.Split(strings.Trim(parsed.Path, "/"), "/")[0]
+	return domain, slug, slug != ""
+}
+
+// getVariantAnalytics returns the clicks of each variant of an A/B link
+func getVariantAnalytics(db *sqlx.DB, domain, slug string) ([]models.VariantAnalytics, error) {
+	stats, err := pg.GetURLVariantStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]models.VariantAnalytics, 0)
+	for _, stat := range stats {
+		variants = append(variants, models.VariantAnalytics{
+			Variant: stat.Variant,
+			Count:   stat.Counter,
+		})
+	}
+	return variants, nil
+}
+
+// getSourceAnalytics returns the clicks of a link from each source and
+// medium, most clicked first
+func getSourceAnalytics(db *sqlx.DB, domain, slug string) ([]models.SourceAnalytics, error) {
+	stats, err := pg.GetURLSourceStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]models.SourceAnalytics, 0)
+	for _, stat := range stats {
+		sources = append(sources, models.SourceAnalytics{
+			Source: stat.Source,
+			Medium: stat.Medium,
+			Count:  stat.Counter,
+		})
+	}
+	return sources, nil
+}
+
+// getCampaignAnalytics returns the clicks of a link tagged with each
+// utm_campaign, most clicked first
+func getCampaignAnalytics(db *sqlx.DB, domain, slug string) ([]models.CampaignAnalytics, error) {
+	stats, err := pg.GetURLCampaignStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]models.CampaignAnalytics, 0)
+	for _, stat := range stats {
+		campaigns = append(campaigns, models.CampaignAnalytics{
+			Campaign: stat.Campaign,
+			Count:    stat.Counter,
+		})
+	}
+	return campaigns, nil
+}
+
+// getBotAnalytics returns the hits of a link by bots for each reason they
+// were told apart, most hits first
+func getBotAnalytics(db *sqlx.DB, domain, slug string) ([]models.BotAnalytics, error) {
+	stats, err := pg.GetURLBotStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]models.BotAnalytics, 0)
+	for _, stat := range stats {
+		hits = append(hits, models.BotAnalytics{
+			Reason: stat.Reason,
+			Count:  stat.Counter,
+		})
+	}
+	return hits, nil
+}
+
+// getAnalytics returns the total clicks of a slug and its visits by location
+// in descending order of count
+func getAnalytics(db *sqlx.DB, domain, slug string) (int, []models.Analytics, error) {
+	stats, err := pg.GetURLStats(db, map[string]interface{}{
+		"domain": domain,
+		"slug":   slug,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var clicks int
+	analytics := make([]models.Analytics, 0)
+
+	for _, stat := range stats {
+		clicks += stat.Counter
+		analytics = append(analytics, models.Analytics{
+			Country: stat.Country,
+			State:   stat.State,
+			Count:   stat.Counter,
+		})
+	}
+
+	// sort in descending order of count
+	sort.Slice(analytics, func(i, j int) bool { return analytics[i].Count > analytics[j].Count })
+	return clicks, analytics, nil
+}
@@ -6,10 +6,15 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	url2 "net/url"
 	"testing"
+	"time"
 
+	"github.com/guregu/null"
 	"github.com/jasontthai/tinyalias/models"
+	"github.com/jasontthai/tinyalias/pg"
 	"github.com/jasontthai/tinyalias/test"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -138,3 +143,315 @@ func TestAPICreateURL(t *testing.T) {
 		assert.Equal(t, float64(1539729574), rawRes["expiration"].(float64))
 	}
 }
+
+func TestForwarding(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	router.GET("/:slug/*path", Get)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=%v&alias=%v&forward_query=keep&forward_path=true",
+			url2.QueryEscape("https://example.com/docs?ref=short"), slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v/setup?ref=other&utm_source=mail", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com/docs/setup?ref=short&utm_source=mail", w.Header().Get("Location"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v-plain", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v-plain/setup", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, fmt.Sprintf("/?%v=%v-plain", NotFoundQuery, slug), w.Header().Get("Location"))
+	}
+}
+
+func TestOneTimeURL(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v&max_clicks=1", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	// bots neither use up the only click nor learn where it goes
+	for header, value := range map[string]string{
+		"User-Agent": "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+		"Purpose":    "prefetch",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v", slug), nil)
+		req.Header.Set(header, value)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get("Location"))
+		body, _ := ioutil.ReadAll(w.Body)
+		assert.NotContains(t, string(body), "https://example.com")
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, fmt.Sprintf("/?%v=%v", ExpiredQuery, slug), w.Header().Get("Location"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/create?url=example.com&max_clicks=-1", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+}
+
+func TestRedirectOptions(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v&redirect_code=301&cache_control=%v&no_index=true&referrer_policy=no-referrer",
+			slug, url2.QueryEscape("public, max-age=3600")), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 301, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+		assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+		assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/create?url=example.com&redirect_code=200", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+}
+
+func TestBotActivatesPendingURL(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	db, err := sqlx.Open("postgres", test.GetTestPgURL())
+	assert.Nil(t, err)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	// a link only ever unfurled is in use but not clicked
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+slug, nil)
+		req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+	}
+	url, err := pg.GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Active, url.Status)
+	assert.Equal(t, 0, url.Counter)
+}
+
+func TestPreview(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v&max_clicks=1&description=%v",
+			slug, url2.QueryEscape("Our example")), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	// previews do not use up the only click nor reveal where it goes
+	for _, path := range []string{"/%v+", "/%v?preview=1"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf(path, slug), nil)
+		req.AddCookie(&http.Cookie{Name: PreviewCookie, Value: "true"})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		body, _ := ioutil.ReadAll(w.Body)
+		assert.NotContains(t, string(body), "https://example.com")
+		assert.Contains(t, string(body), "Our example")
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v?preview=0", slug), nil)
+		req.AddCookie(&http.Cookie{Name: PreviewCookie, Value: "true"})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	}
+
+	// links without a click limit show their destination
+	slug = models.GenerateSlug(6)
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v+", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		body, _ := ioutil.ReadAll(w.Body)
+		assert.Contains(t, string(body), "https://example.com")
+	}
+}
+
+func TestCard(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v&card_title=%v&card_image=%v",
+			slug, url2.QueryEscape("Our launch"), url2.QueryEscape("https://example.com/card.png")), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+slug, nil)
+		req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		body, _ := ioutil.ReadAll(w.Body)
+		assert.Contains(t, string(body), `<meta property="og:title" content="Our launch">`)
+		assert.Contains(t, string(body), `<meta property="og:image" content="https://example.com/card.png">`)
+		assert.NotContains(t, string(body), `content="https://example.com"`)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+slug, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_0)")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+		assert.Equal(t, "User-Agent", w.Header().Get("Vary"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&card_image=%v",
+			url2.QueryEscape("javascript:alert(1)")), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	router := test.GetTestRouter()
+	router.GET("/create", APICreateURL)
+	router.GET("/:slug", Get)
+	slug := models.GenerateSlug(6)
+
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v&forward_query=keep", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v.qr?size=128", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v.qr?format=svg", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v.qr?level=X", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code)
+	}
+	// the source marker of scans is not forwarded
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v?ta_source=qr", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	}
+
+	// used up links have no QR code
+	slug = models.GenerateSlug(6)
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/create?url=example.com&alias=%v&max_clicks=1", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+slug, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/%v.qr", slug), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 302, w.Code)
+		assert.Equal(t, fmt.Sprintf("/?%v=%v", ExpiredQuery, slug), w.Header().Get("Location"))
+	}
+}
+
+func TestIsSameLink(t *testing.T) {
+	existing := &models.URL{Url: "https://example.com", Username: "alice"}
+	assert.True(t, isSameLink(existing, "https://example.com", "alice"))
+	assert.False(t, isSameLink(existing, "https://example.org", "alice"))
+	assert.False(t, isSameLink(existing, "https://example.com", "bob"))
+	assert.False(t, isSameLink(existing, "https://example.com", ""))
+
+	existing.Deleted = null.TimeFrom(time.Now())
+	assert.False(t, isSameLink(existing, "https://example.com", "alice"))
+}
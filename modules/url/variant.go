@@ -0,0 +1,31 @@
+package url
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+)
+
+const (
+	// VariantCookie keeps a visitor on the same variant of an A/B link. It is
+	// scoped to the path of the link so every link has its own.
+	VariantCookie    = "tinyalias_variant"
+	variantCookieAge = 30 * 24 * 60 * 60
+)
+
+// assignVariant returns the variant of an A/B link a visitor was assigned to,
+// choosing one by weight on the first visit
+func assignVariant(c *gin.Context, url *models.URL) string {
+	if len(url.Variants) == 0 {
+		return ""
+	}
+	if name, err := c.Cookie(VariantCookie); err == nil && url.Variants.Find(name) != nil {
+		return name
+	}
+
+	variant := url.Variants.Choose()
+	if variant == nil {
+		return ""
+	}
+	c.SetCookie(VariantCookie, variant.Name, variantCookieAge, "/"+url.Slug, "", false, true)
+	return variant.Name
+}
@@ -0,0 +1,56 @@
+package url
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignVariant(t *testing.T) {
+	url := &models.URL{
+		Slug: "abtest",
+		Variants: models.Variants{
+			{Name: "A", URL: "https://example.com/a", Weight: 1},
+			{Name: "B", URL: "https://example.com/b", Weight: 1},
+		},
+	}
+
+	{
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/abtest", nil)
+		variant := assignVariant(c, url)
+		assert.NotNil(t, url.Variants.Find(variant))
+		cookie := w.Header().Get("Set-Cookie")
+		assert.Contains(t, cookie, VariantCookie+"="+variant)
+		assert.Contains(t, cookie, "Path=/abtest")
+	}
+	{
+		// returning visitors keep their variant
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/abtest", nil)
+		c.Request.AddCookie(&http.Cookie{Name: VariantCookie, Value: "B"})
+		assert.Equal(t, "B", assignVariant(c, url))
+		assert.Empty(t, w.Header().Get("Set-Cookie"))
+	}
+	{
+		// variants that were removed are assigned again
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/abtest", nil)
+		c.Request.AddCookie(&http.Cookie{Name: VariantCookie, Value: "C"})
+		assert.NotEqual(t, "C", assignVariant(c, url))
+		assert.NotEmpty(t, w.Header().Get("Set-Cookie"))
+	}
+	{
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/plain", nil)
+		assert.Equal(t, "", assignVariant(c, &models.URL{Slug: "plain"}))
+	}
+}
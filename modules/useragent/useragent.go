@@ -0,0 +1,143 @@
+package useragent
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Other is the name of a browser, OS or device class no rule matched
+const Other = "Other"
+
+// Device classes of the default rules
+const (
+	DeviceBot     = "Bot"
+	DeviceTablet  = "Tablet"
+	DeviceTV      = "TV"
+	DeviceConsole = "Console"
+	DeviceMobile  = "Mobile"
+	DeviceDesktop = "Desktop"
+)
+
+// Rules is the file of User-Agent patterns, set with $USER_AGENT_RULES. It
+// can be updated without a new build, the rules are read on first use.
+var Rules = "static/useragents.json"
+
+var (
+	once          sync.Once
+	defaultParser *Parser
+)
+
+func init() {
+	if rules := os.Getenv("USER_AGENT_RULES"); rules != "" {
+		Rules = rules
+	}
+}
+
+// Result is the browser family, OS family and device class of a User-Agent
+type Result struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+// rule names a User-Agent matching pattern and not matching not. RE2 has no
+// lookaheads, so exclusions are a second pattern.
+type rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Not     string `json:"not"`
+
+	pattern *regexp.Regexp
+	not     *regexp.Regexp
+}
+
+func (r *rule) compile() error {
+	var err error
+	if r.pattern, err = regexp.Compile(r.Pattern); err != nil {
+		return err
+	}
+	if r.Not != "" {
+		if r.not, err = regexp.Compile(r.Not); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rule) match(userAgent string) bool {
+	return r.pattern.MatchString(userAgent) && (r.not == nil || !r.not.MatchString(userAgent))
+}
+
+// Parser classifies User-Agents with ordered lists of rules, the first
+// matching rule of each list wins
+type Parser struct {
+	browsers []rule
+	os       []rule
+	devices  []rule
+}
+
+// Load reads a rules file
+func Load(path string) (*Parser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var parser struct {
+		Browsers []rule `json:"browsers"`
+		OS       []rule `json:"os"`
+		Devices  []rule `json:"devices"`
+	}
+	if err := json.NewDecoder(file).Decode(&parser); err != nil {
+		return nil, err
+	}
+	for _, rules := range [][]rule{parser.Browsers, parser.OS, parser.Devices} {
+		for i := range rules {
+			if err := rules[i].compile(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Parser{browsers: parser.Browsers, os: parser.OS, devices: parser.Devices}, nil
+}
+
+// Parse returns the browser, OS and device class of a User-Agent
+func (p *Parser) Parse(userAgent string) Result {
+	return Result{
+		Browser: first(p.browsers, userAgent),
+		OS:      first(p.os, userAgent),
+		Device:  first(p.devices, userAgent),
+	}
+}
+
+func first(rules []rule, userAgent string) string {
+	if userAgent == "" {
+		return Other
+	}
+	for i := range rules {
+		if rules[i].match(userAgent) {
+			return rules[i].Name
+		}
+	}
+	return Other
+}
+
+// Parse classifies a User-Agent with the rules file, loaded on first use and
+// shared by all callers. Everything is Other when it cannot be loaded.
+func Parse(userAgent string) Result {
+	once.Do(func() {
+		var err error
+		if defaultParser, err = Load(Rules); err != nil {
+			log.WithField("rules", Rules).WithError(err).Error("error loading user agent rules")
+		}
+	})
+	if defaultParser == nil {
+		return Result{Browser: Other, OS: Other, Device: Other}
+	}
+	return defaultParser.Parse(userAgent)
+}
@@ -0,0 +1,104 @@
+package useragent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := Load("../../static/useragents.json")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		userAgent string
+		expected  Result
+	}{
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 12_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0 Mobile/15E148 Safari/604.1",
+			Result{Browser: "Safari", OS: "iOS", Device: DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 12_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/71.0.3578.77 Mobile/15E148 Safari/605.1",
+			Result{Browser: "Chrome", OS: "iOS", Device: DeviceTablet},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/71.0.3578.99 Mobile Safari/537.36",
+			Result{Browser: "Chrome", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 8.1.0; SM-T580) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/8.2 Chrome/63.0.3239.111 Safari/537.36",
+			Result{Browser: "Samsung Internet", OS: "Android", Device: DeviceTablet},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.17763",
+			Result{Browser: "Edge", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.14; rv:64.0) Gecko/20100101 Firefox/64.0",
+			Result{Browser: "Firefox", OS: "macOS", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (X11; CrOS x86_64 11151.59.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/71.0.3578.94 Safari/537.36",
+			Result{Browser: "Chrome", OS: "Chrome OS", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; Lumia 950) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Mobile Safari/537.36 Edge/15.15063",
+			Result{Browser: "Edge", OS: "Windows Phone", Device: DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (Web0S; Linux/SmartTV) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/38.0.2125.122 Safari/537.36",
+			Result{Browser: "Chrome", OS: "Linux", Device: DeviceTV},
+		},
+		{
+			"Mozilla/5.0 (PlayStation 4 5.55) AppleWebKit/601.2 (KHTML, like Gecko)",
+			Result{Browser: Other, OS: Other, Device: DeviceConsole},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Result{Browser: Other, OS: Other, Device: DeviceBot},
+		},
+		{
+			"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			Result{Browser: Other, OS: Other, Device: DeviceBot},
+		},
+		{
+			"curl/7.54.0",
+			Result{Browser: Other, OS: Other, Device: DeviceBot},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 9; CUBOT X19) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/72.0.3626.105 Mobile Safari/537.36",
+			Result{Browser: "Chrome", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			"",
+			Result{Browser: Other, OS: Other, Device: Other},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, parser.Parse(test.userAgent), test.userAgent)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "useragent")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"browsers": [{"name": "Tiny", "pattern": "TinyBrowser/"}]}`), 0644))
+	parser, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Browser: "Tiny", OS: Other, Device: Other}, parser.Parse("TinyBrowser/1.0"))
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"os": [{"name": "Broken", "pattern": "("}]}`), 0644))
+	_, err = Load(path)
+	assert.NotNil(t, err)
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
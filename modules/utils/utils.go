@@ -4,8 +4,10 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jasontthai/tinyalias/models"
 	"github.com/jasontthai/tinyalias/modules/auth"
 )
 
@@ -15,20 +17,40 @@ const (
 
 	DefaultLimit  = 20
 	DefaultOffset = 0
+
+	DefaultTrashRetentionDays = 30
 )
 
 var BaseUrl string
 var ApiBaseUrl string
 
+// IPHashSecret keys the hashes of the ips of clicks
+var IPHashSecret string
+
+// CountBots counts the clicks of bots like those of humans
+var CountBots bool
+
+// TrashRetention is how long deleted links can be restored before they are purged
+var TrashRetention time.Duration
+
 func init() {
 	BaseUrl = os.Getenv("BASE_URL")
 	ApiBaseUrl = os.Getenv("API_BASE_URL")
+	IPHashSecret = os.Getenv("IP_HASH_SECRET")
+	CountBots = os.Getenv("COUNT_BOTS") == "true"
+
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = DefaultTrashRetentionDays
+	}
+	TrashRetention = time.Duration(days) * 24 * time.Hour
 }
 
 func HandleHtmlResponse(c *gin.Context, statusCode int, template string, h gin.H) {
 	user := auth.GetAuthenticatedUser(c)
 	if user != nil {
 		h["user"] = user.Username
+		h["admin"] = user.Role == models.RoleAdmin
 	}
 	h[BaseURL] = BaseUrl
 	h[ApiBaseURL] = ApiBaseUrl
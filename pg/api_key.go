@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetAPIKeyByHash(db *sqlx.DB, hash string) (*models.APIKey, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("api_keys").Where(squirrel.Eq{"hash": hash})
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Queryx(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var key models.APIKey
+		if err := rows.StructScan(&key); err != nil {
+			return nil, err
+		}
+		return &key, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func GetAPIKeys(db *sqlx.DB, clauses map[string]interface{}) ([]models.APIKey, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("api_keys").OrderBy("created desc")
+
+	if username, ok := clauses["username"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"username": username})
+	}
+
+	if revoked, ok := clauses["revoked"].(bool); ok {
+		if revoked {
+			sb = sb.Where("revoked IS NOT NULL")
+		} else {
+			sb = sb.Where("revoked IS NULL")
+		}
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []models.APIKey
+	if err := db.Select(&keys, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func CreateAPIKey(db *sqlx.DB, key *models.APIKey) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("api_keys").Columns("username, name, prefix, hash, scopes, created").
+		Values(key.Username, key.Name, key.Prefix, key.Hash, key.Scopes, key.Created).
+		Suffix("RETURNING id")
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return db.Get(&key.ID, sqlStr, args...)
+}
+
+// RevokeAPIKey revokes the key with the given id. If username is not empty
+// only a key owned by that user is revoked.
+func RevokeAPIKey(db *sqlx.DB, id int64, username string) (bool, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Update("api_keys").Set("revoked", time.Now()).
+		Where(squirrel.Eq{"id": id}).Where("revoked IS NULL")
+	if username != "" {
+		sb = sb.Where(squirrel.Eq{"username": username})
+	}
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.Exec(sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func UpdateAPIKeyLastUsed(db *sqlx.DB, key *models.APIKey) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Update("api_keys").Set("last_used", time.Now()).Where(squirrel.Eq{"id": key.ID})
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
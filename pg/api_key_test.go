@@ -0,0 +1,57 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey(t *testing.T) {
+	db := setup(t)
+
+	raw, err := models.GenerateAPIKey()
+	assert.Nil(t, err)
+	username := models.GenerateSlug(8)
+	key := &models.APIKey{
+		Username: username,
+		Name:     "test",
+		Prefix:   models.APIKeyPrefix(raw),
+		Hash:     models.HashAPIKey(raw),
+		Scopes:   []string{models.ScopeLinksRead},
+		Created:  time.Now(),
+	}
+
+	// Test CreateAPIKey
+	err = CreateAPIKey(db, key)
+	assert.Nil(t, err)
+	assert.NotZero(t, key.ID)
+
+	// Test GetAPIKeyByHash
+	returnedKey, err := GetAPIKeyByHash(db, models.HashAPIKey(raw))
+	assert.Nil(t, err)
+	assert.Equal(t, username, returnedKey.Username)
+	assert.True(t, returnedKey.HasScope(models.ScopeLinksRead))
+
+	// Test UpdateAPIKeyLastUsed
+	err = UpdateAPIKeyLastUsed(db, returnedKey)
+	assert.Nil(t, err)
+
+	// Test RevokeAPIKey only revokes keys of the owner
+	revoked, err := RevokeAPIKey(db, key.ID, "someone-else")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = RevokeAPIKey(db, key.ID, username)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// Test GetAPIKeys
+	keys, err := GetAPIKeys(db, map[string]interface{}{
+		"username": username,
+		"revoked":  false,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(keys))
+}
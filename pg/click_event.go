@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetClickEvents returns the clicks of a url, latest first. "from" and "to"
+// limit them to a time range.
+func GetClickEvents(db *sqlx.DB, clauses map[string]interface{}) ([]models.ClickEvent, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("click_events").OrderBy("clicked desc", "id desc")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	if from, ok := clauses["from"].(time.Time); ok {
+		sb = sb.Where("clicked >= ?", from)
+	}
+
+	if to, ok := clauses["to"].(time.Time); ok {
+		sb = sb.Where("clicked < ?", to)
+	}
+
+	if limit, ok := clauses["_limit"].(uint64); ok {
+		sb = sb.Limit(limit)
+	}
+
+	if offset, ok := clauses["_offset"].(uint64); ok {
+		sb = sb.Offset(offset)
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.ClickEvent
+
+	if err := db.Select(&events, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func CreateClickEvent(db *sqlx.DB, event *models.ClickEvent) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("click_events").
+		Columns("domain, slug, clicked, ip_hash, referrer, user_agent, language, variant, source, medium, campaign, browser, os, device, bot, country, state").
+		Values(event.Domain, event.Slug, event.Clicked, event.IPHash, event.Referrer, event.UserAgent,
+			event.Language, event.Variant, event.Source, event.Medium, event.Campaign, event.Browser, event.OS,
+			event.Device, event.Bot, event.Country, event.State).
+		Suffix("RETURNING id")
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return db.Get(&event.ID, sqlStr, args...)
+}
@@ -0,0 +1,56 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickEvent(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:  "https://example.com",
+		Slug: slug,
+	})
+	assert.Nil(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, referrer := range []string{"https://twitter.com/", "", "https://news.ycombinator.com/"} {
+		event := &models.ClickEvent{
+			Slug:      slug,
+			Clicked:   now.Add(time.Duration(i-2) * time.Hour),
+			IPHash:    models.HashIP("203.0.113.7", "secret"),
+			Referrer:  referrer,
+			UserAgent: "Mozilla/5.0",
+			Language:  "en-US",
+			Source:    models.ClickSourceQR,
+			Country:   "Germany",
+		}
+		err := CreateClickEvent(db, event)
+		assert.Nil(t, err)
+		assert.NotEqual(t, int64(0), event.ID)
+	}
+
+	events, err := GetClickEvents(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "https://news.ycombinator.com/", events[0].Referrer)
+
+	events, err = GetClickEvents(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+		"from":   now.Add(-90 * time.Minute),
+		"to":     now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "", events[0].Referrer)
+	assert.Equal(t, "Germany", events[0].Country)
+}
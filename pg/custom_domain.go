@@ -0,0 +1,143 @@
+package pg
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetCustomDomain(db *sqlx.DB, host string) (*models.CustomDomain, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("custom_domains").Where(squirrel.Eq{"host": host})
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Queryx(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var domain models.CustomDomain
+		if err := rows.StructScan(&domain); err != nil {
+			return nil, err
+		}
+		return &domain, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func GetCustomDomains(db *sqlx.DB, clauses map[string]interface{}) ([]models.CustomDomain, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("custom_domains").OrderBy("host asc")
+
+	if username, ok := clauses["username"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"username": username})
+	}
+
+	if verified, ok := clauses["verified"].(bool); ok {
+		if verified {
+			sb = sb.Where("verified IS NOT NULL")
+		} else {
+			sb = sb.Where("verified IS NULL")
+		}
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []models.CustomDomain
+	if err := db.Select(&domains, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+func CreateCustomDomain(db *sqlx.DB, domain *models.CustomDomain) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("custom_domains").Columns("host, username, token, verified, created").
+		Values(domain.Host, domain.Username, domain.Token, domain.Verified, domain.Created)
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClaimCustomDomain hands a domain nobody verified yet over to the user of
+// domain with its new token, and reports whether it was still unverified
+func ClaimCustomDomain(db *sqlx.DB, domain *models.CustomDomain) (bool, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Update("custom_domains").
+		Set("username", domain.Username).
+		Set("token", domain.Token).
+		Set("created", domain.Created).
+		Where(squirrel.Eq{"host": domain.Host}).Where("verified IS NULL")
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.Exec(sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func VerifyCustomDomain(db *sqlx.DB, host string, verified time.Time) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Update("custom_domains").Set("verified", verified).Where(squirrel.Eq{"host": host})
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteCustomDomain deletes a domain of username, or of anyone if username
+// is empty, and reports whether there was one
+func DeleteCustomDomain(db *sqlx.DB, host, username string) (bool, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Delete("custom_domains").Where(squirrel.Eq{"host": host})
+	if username != "" {
+		sb = sb.Where(squirrel.Eq{"username": username})
+	}
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.Exec(sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,99 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomDomain(t *testing.T) {
+	db := setup(t)
+
+	host := strings.ToLower(models.GenerateSlug(8)) + ".example.com"
+	err := CreateCustomDomain(db, &models.CustomDomain{
+		Host:     host,
+		Username: "user",
+		Token:    "token",
+		Created:  time.Now(),
+	})
+	assert.Nil(t, err)
+
+	// Test GetCustomDomain
+	domain, err := GetCustomDomain(db, host)
+	assert.Nil(t, err)
+	assert.False(t, domain.Verified.Valid)
+
+	// Test ClaimCustomDomain
+	claimed, err := ClaimCustomDomain(db, &models.CustomDomain{
+		Host:     host,
+		Username: "other",
+		Token:    "other-token",
+		Created:  time.Now(),
+	})
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+	domain, err = GetCustomDomain(db, host)
+	assert.Nil(t, err)
+	assert.Equal(t, "other", domain.Username)
+
+	claimed, err = ClaimCustomDomain(db, &models.CustomDomain{
+		Host:     host,
+		Username: "user",
+		Token:    "token",
+		Created:  time.Now(),
+	})
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	// Test VerifyCustomDomain
+	err = VerifyCustomDomain(db, host, time.Now())
+	assert.Nil(t, err)
+
+	// verified domains cannot be claimed
+	claimed, err = ClaimCustomDomain(db, &models.CustomDomain{
+		Host:     host,
+		Username: "other",
+		Token:    "other-token",
+		Created:  time.Now(),
+	})
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+
+	domains, err := GetCustomDomains(db, map[string]interface{}{
+		"username": "user",
+		"verified": true,
+	})
+	assert.Nil(t, err)
+	var found bool
+	for _, d := range domains {
+		if d.Host == host {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	// Test the same slug on the default and the custom domain
+	slug := models.GenerateSlug(6)
+	err = CreateURL(db, &models.URL{Url: "https://example.com", Slug: slug})
+	assert.Nil(t, err)
+	err = CreateURL(db, &models.URL{Url: "https://example.org", Domain: host, Slug: slug})
+	assert.Nil(t, err)
+	err = CreateURL(db, &models.URL{Url: "https://example.net", Domain: host, Slug: slug})
+	assert.True(t, IsUniqueViolation(err))
+
+	url, err := GetURL(db, host, slug)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.org", url.Url)
+
+	// Test DeleteCustomDomain
+	deleted, err := DeleteCustomDomain(db, host, "other")
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+
+	deleted, err = DeleteCustomDomain(db, host, "user")
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+}
@@ -0,0 +1,91 @@
+package pg
+
+import (
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetReservedSlug(db *sqlx.DB, slug string) (*models.ReservedSlug, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("reserved_slugs").Where(squirrel.Eq{"slug": slug})
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Queryx(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var reserved models.ReservedSlug
+		if err := rows.StructScan(&reserved); err != nil {
+			return nil, err
+		}
+		return &reserved, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func GetReservedSlugs(db *sqlx.DB, clauses map[string]interface{}) ([]models.ReservedSlug, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("reserved_slugs").OrderBy("slug asc")
+
+	if reason, ok := clauses["reason"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"reason": reason})
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var reserved []models.ReservedSlug
+
+	if err := db.Select(&reserved, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return reserved, nil
+}
+
+func CreateReservedSlug(db *sqlx.DB, reserved *models.ReservedSlug) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("reserved_slugs").Columns("slug, reason, username, created").
+		Values(reserved.Slug, reserved.Reason, reserved.Username, reserved.Created).
+		Suffix("ON CONFLICT (slug) DO UPDATE SET reason = EXCLUDED.reason")
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func DeleteReservedSlug(db *sqlx.DB, slug string) (bool, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Delete("reserved_slugs").Where(squirrel.Eq{"slug": slug})
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.Exec(sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
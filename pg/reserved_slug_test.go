@@ -0,0 +1,56 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedSlug(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(8)
+	err := CreateReservedSlug(db, &models.ReservedSlug{
+		Slug:     slug,
+		Reason:   models.ReservedBrand,
+		Username: "admin",
+		Created:  time.Now(),
+	})
+	assert.Nil(t, err)
+
+	// Test GetReservedSlug
+	reserved, err := GetReservedSlug(db, slug)
+	assert.Nil(t, err)
+	assert.Equal(t, models.ReservedBrand, reserved.Reason)
+
+	// Test updating the reason
+	err = CreateReservedSlug(db, &models.ReservedSlug{
+		Slug:    slug,
+		Reason:  models.ReservedProfanity,
+		Created: time.Now(),
+	})
+	assert.Nil(t, err)
+
+	reservedSlugs, err := GetReservedSlugs(db, map[string]interface{}{
+		"reason": models.ReservedProfanity,
+	})
+	assert.Nil(t, err)
+	var found bool
+	for _, r := range reservedSlugs {
+		if r.Slug == slug {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	// Test DeleteReservedSlug
+	deleted, err := DeleteReservedSlug(db, slug)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = DeleteReservedSlug(db, slug)
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+}
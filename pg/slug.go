@@ -0,0 +1,24 @@
+package pg
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const uniqueViolation = "23505"
+
+func NextSlugSequence(db *sqlx.DB) (int64, error) {
+	var n int64
+	if err := db.Get(&n, "SELECT nextval('url_slug_seq')"); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// IsUniqueViolation reports whether err is caused by a duplicate key
+func IsUniqueViolation(err error) bool {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code == uniqueViolation
+	}
+	return false
+}
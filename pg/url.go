@@ -10,10 +10,10 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func GetURL(db *sqlx.DB, slug string) (*models.URL, error) {
+func GetURL(db *sqlx.DB, domain, slug string) (*models.URL, error) {
 	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
 	sb := psql.Select("*").
-		From("urls").Where(squirrel.Eq{"slug": slug})
+		From("urls").Where(squirrel.Eq{"domain": domain, "slug": slug})
 
 	sqlStr, args, err := sb.ToSql()
 	if err != nil {
@@ -41,6 +41,10 @@ func GetURLs(db *sqlx.DB, clauses map[string]interface{}) ([]models.URL, error)
 	sb := psql.Select("*").
 		From("urls")
 
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
 	if slug, ok := clauses["slug"].(string); ok {
 		sb = sb.Where(squirrel.Eq{"slug": slug})
 	}
@@ -57,6 +61,13 @@ func GetURLs(db *sqlx.DB, clauses map[string]interface{}) ([]models.URL, error)
 		sb = sb.Where(squirrel.Eq{"username": username})
 	}
 
+	// urls in the trash are only returned when asked for
+	if deleted, ok := clauses["deleted"].(bool); ok && deleted {
+		sb = sb.Where("deleted IS NOT NULL")
+	} else {
+		sb = sb.Where("deleted IS NULL")
+	}
+
 	if limit, ok := clauses["_limit"].(uint64); ok {
 		sb = sb.Limit(limit)
 	}
@@ -104,6 +115,10 @@ func GetURLCount(db *sqlx.DB, clauses map[string]interface{}) (int, error) {
 	sb := psql.Select("count(*)").
 		From("urls")
 
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
 	if slug, ok := clauses["slug"].(string); ok {
 		sb = sb.Where(squirrel.Eq{"slug": slug})
 	}
@@ -120,6 +135,13 @@ func GetURLCount(db *sqlx.DB, clauses map[string]interface{}) (int, error) {
 		sb = sb.Where(squirrel.Eq{"username": username})
 	}
 
+	// urls in the trash are only returned when asked for
+	if deleted, ok := clauses["deleted"].(bool); ok && deleted {
+		sb = sb.Where("deleted IS NOT NULL")
+	} else {
+		sb = sb.Where("deleted IS NULL")
+	}
+
 	// search field
 	if like, ok := clauses["_like"].(string); ok && like != "" {
 		sb = sb.Where(fmt.Sprintf("(slug ilike '%v' OR url ilike '%v' OR username ilike '%v')", like, like, like))
@@ -140,8 +162,8 @@ func GetURLCount(db *sqlx.DB, clauses map[string]interface{}) (int, error) {
 
 func CreateURL(db *sqlx.DB, url *models.URL) error {
 	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
-	sb := psql.Insert("urls").Columns("url, slug, ip, counter, created, updated, password, expired, mindful, username").
-		Values(url.Url, url.Slug, url.IP, url.Counter, url.Created, url.Updated, url.Password, url.Expired, url.Mindful, url.Username)
+	sb := psql.Insert("urls").Columns("url, domain, slug, ip, counter, created, updated, password, expired, mindful, username, forward_query, forward_path, targets, geo_targets, variants, max_clicks, not_before, redirect_code, cache_control, no_index, referrer_policy, description, card").
+		Values(url.Url, url.Domain, url.Slug, url.IP, url.Counter, url.Created, url.Updated, url.Password, url.Expired, url.Mindful, url.Username, url.ForwardQuery, url.ForwardPath, url.Targets, url.GeoTargets, url.Variants, url.MaxClicks, url.NotBefore, url.RedirectCode, url.CacheControl, url.NoIndex, url.ReferrerPolicy, url.Description, url.Card)
 	sqlStr, args, err := sb.ToSql()
 	if err != nil {
 		return err
@@ -159,7 +181,7 @@ func UpdateURL(db *sqlx.DB, url *models.URL) error {
 	clauses["counter"] = url.Counter
 	clauses["status"] = url.Status
 	clauses["updated"] = time.Now()
-	sb := psql.Update("urls").SetMap(clauses).Where(squirrel.Eq{"slug": url.Slug})
+	sb := psql.Update("urls").SetMap(clauses).Where(squirrel.Eq{"domain": url.Domain, "slug": url.Slug})
 	sqlStr, args, err := sb.ToSql()
 	if err != nil {
 		return err
@@ -171,18 +193,111 @@ func UpdateURL(db *sqlx.DB, url *models.URL) error {
 	return nil
 }
 
-func DeleteURL(db *sqlx.DB, longUrl, slug string) error {
-	if longUrl == "" && slug == "" {
+// ClickURL counts a click of a url and activates it if it is pending. The
+// limit of click-limited urls is checked in the same statement so concurrent
+// clicks cannot go over it. It returns sql.ErrNoRows if the url has no clicks
+// left.
+func ClickURL(db *sqlx.DB, domain, slug string) (*models.URL, error) {
+	var url models.URL
+	err := db.Get(&url, `UPDATE urls SET counter = counter + 1,
+		status = CASE WHEN status = 'pending' THEN 'active' ELSE status END, updated = NOW()
+		WHERE domain = $1 AND slug = $2 AND deleted IS NULL AND (max_clicks = 0 OR counter < max_clicks)
+		RETURNING *`, domain, slug)
+	if err != nil {
+		return nil, err
+	}
+	return &url, nil
+}
+
+// ActivateURL activates a pending url without counting a click. It returns
+// sql.ErrNoRows if the url is not pending.
+func ActivateURL(db *sqlx.DB, domain, slug string) (*models.URL, error) {
+	var url models.URL
+	err := db.Get(&url, `UPDATE urls SET status = 'active', updated = NOW()
+		WHERE domain = $1 AND slug = $2 AND deleted IS NULL AND status = 'pending'
+		RETURNING *`, domain, slug)
+	if err != nil {
+		return nil, err
+	}
+	return &url, nil
+}
+
+func EditURL(db *sqlx.DB, url *models.URL) error {
+	return editURL(db, url)
+}
+
+func editURL(db sqlx.Execer, url *models.URL) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	clauses := make(map[string]interface{})
+	clauses["url"] = url.Url
+	clauses["password"] = url.Password
+	clauses["expired"] = url.Expired
+	clauses["mindful"] = url.Mindful
+	clauses["forward_query"] = url.ForwardQuery
+	clauses["forward_path"] = url.ForwardPath
+	clauses["targets"] = url.Targets
+	clauses["geo_targets"] = url.GeoTargets
+	clauses["variants"] = url.Variants
+	clauses["max_clicks"] = url.MaxClicks
+	clauses["not_before"] = url.NotBefore
+	clauses["redirect_code"] = url.RedirectCode
+	clauses["cache_control"] = url.CacheControl
+	clauses["no_index"] = url.NoIndex
+	clauses["referrer_policy"] = url.ReferrerPolicy
+	clauses["description"] = url.Description
+	clauses["card"] = url.Card
+	clauses["status"] = url.Status
+	clauses["updated"] = time.Now()
+	sb := psql.Update("urls").SetMap(clauses).Where(squirrel.Eq{"domain": url.Domain, "slug": url.Slug})
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateURLMetadata stores the metadata fetched from the destination of a
+// url. It is not an edit so updated is left alone.
+func UpdateURLMetadata(db *sqlx.DB, domain, slug string, metadata models.Metadata) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Update("urls").Set("metadata", metadata).
+		Where(squirrel.Eq{"domain": domain, "slug": slug})
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TrashURL moves a url to the trash. Its slug stays reserved until it is purged.
+func TrashURL(db *sqlx.DB, domain, slug string) error {
+	if slug == "" {
 		return fmt.Errorf("missing required field")
 	}
 	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
-	sb := psql.Delete("urls")
-	if longUrl != "" {
-		sb = sb.Where(squirrel.Eq{"url": longUrl})
+	sb := psql.Update("urls").Set("deleted", time.Now()).
+		Where(squirrel.Eq{"domain": domain, "slug": slug}).Where("deleted IS NULL")
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
 	}
-	if slug != "" {
-		sb = sb.Where(squirrel.Eq{"slug": slug})
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
 	}
+	return nil
+}
+
+func RestoreURL(db *sqlx.DB, domain, slug string) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Update("urls").Set("deleted", nil).Set("updated", time.Now()).
+		Where(squirrel.Eq{"domain": domain, "slug": slug})
 	sqlStr, args, err := sb.ToSql()
 	if err != nil {
 		return err
@@ -192,3 +307,84 @@ func DeleteURL(db *sqlx.DB, longUrl, slug string) error {
 	}
 	return nil
 }
+
+// PurgeTrashedURLs permanently deletes urls moved to the trash before the
+// given time along with their stats and revisions and returns them. Nothing
+// of a purged url is shown to the next owner of its slug.
+func PurgeTrashedURLs(db *sqlx.DB, before time.Time) ([]models.URL, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var urls []models.URL
+	if err := tx.Select(&urls, "DELETE FROM urls WHERE deleted IS NOT NULL AND deleted < $1 RETURNING *", before); err != nil {
+		return nil, err
+	}
+	if err := deleteURLHistory(tx, urls); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// deleteURLHistory deletes the stats and revisions of deleted urls, which are
+// kept by slug and would otherwise go to the next owner of the slug
+func deleteURLHistory(tx *sqlx.Tx, urls []models.URL) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	for _, url := range urls {
+		for _, table := range []string{"url_stats", "url_variant_stats", "url_source_stats", "url_campaign_stats", "url_device_stats", "url_bot_stats", "url_hourly_stats", "url_revisions"} {
+			sqlStr, args, err := psql.Delete(table).Where(squirrel.Eq{"domain": url.Domain, "slug": url.Slug}).ToSql()
+			if err != nil {
+				return err
+			}
+			if _, err = tx.Exec(sqlStr, args...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ExpireURLs sets the status of urls past their expiration to expired and
+// returns them with their previous status
+func ExpireURLs(db *sqlx.DB) ([]models.URL, error) {
+	var urls []models.URL
+	err := db.Select(&urls, `UPDATE urls SET status = 'expired', updated = NOW()
+		FROM (SELECT domain, slug, status FROM urls WHERE expired IS NOT NULL AND expired < NOW() AND status <> 'expired' FOR UPDATE) old
+		WHERE urls.domain = old.domain AND urls.slug = old.slug
+		RETURNING urls.url, urls.domain, urls.slug, old.status`)
+	if err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// DeletePendingURLs deletes urls that are still pending along with their
+// stats and revisions and returns them. Urls scheduled to start redirecting
+// later are pending until then and kept.
+func DeletePendingURLs(db *sqlx.DB) ([]models.URL, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var urls []models.URL
+	err = tx.Select(&urls, "DELETE FROM urls WHERE status = 'pending' AND deleted IS NULL AND (not_before IS NULL OR not_before <= NOW()) RETURNING *")
+	if err != nil {
+		return nil, err
+	}
+	if err := deleteURLHistory(tx, urls); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
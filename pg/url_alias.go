@@ -0,0 +1,116 @@
+package pg
+
+import (
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLAlias(db *sqlx.DB, domain, alias string) (*models.URLAlias, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_aliases").Where(squirrel.Eq{"domain": domain, "alias": alias})
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Queryx(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var urlAlias models.URLAlias
+		if err := rows.StructScan(&urlAlias); err != nil {
+			return nil, err
+		}
+		return &urlAlias, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func GetURLAliases(db *sqlx.DB, domain, slug string) ([]models.URLAlias, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_aliases").Where(squirrel.Eq{"domain": domain, "slug": slug}).OrderBy("created desc")
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var aliases []models.URLAlias
+	if err := db.Select(&aliases, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// RenameURL changes the slug of a url. The old slug becomes an alias of the
+// new one and the stats and revisions of the url are moved over so they are kept.
+func RenameURL(db *sqlx.DB, domain, oldSlug, newSlug string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := renameURL(tx, domain, oldSlug, newSlug); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EditAndRenameURL saves the edits of a url and renames it at once, neither
+// is kept when the other fails
+func EditAndRenameURL(db *sqlx.DB, url *models.URL, newSlug string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := editURL(tx, url); err != nil {
+		return err
+	}
+	if err := renameURL(tx, url.Domain, url.Slug, newSlug); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func renameURL(tx *sqlx.Tx, domain, oldSlug, newSlug string) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+
+	// aliases of the old slug follow through ON UPDATE CASCADE
+	statements := []squirrel.Sqlizer{
+		psql.Update("urls").Set("slug", newSlug).Set("updated", squirrel.Expr("NOW()")).
+			Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_variant_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_source_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_campaign_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_device_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_bot_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_hourly_stats").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		psql.Update("url_revisions").Set("slug", newSlug).Where(squirrel.Eq{"domain": domain, "slug": oldSlug}),
+		// the new slug may have been an alias of this url before
+		psql.Delete("url_aliases").Where(squirrel.Eq{"domain": domain, "alias": newSlug, "slug": newSlug}),
+		psql.Insert("url_aliases").Columns("domain, alias, slug").Values(domain, oldSlug, newSlug),
+	}
+	for _, statement := range statements {
+		sqlStr, args, err := statement.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(sqlStr, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLAlias(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	newSlug := models.GenerateSlug(7)
+	err := CreateURL(db, &models.URL{
+		Url:  "https://example.com",
+		Slug: slug,
+	})
+	assert.Nil(t, err)
+
+	err = UpsertURLStat(db, &models.URLStat{
+		Slug:    slug,
+		Counter: 1,
+	})
+	assert.Nil(t, err)
+
+	// Test RenameURL
+	err = RenameURL(db, "", slug, newSlug)
+	assert.Nil(t, err)
+
+	_, err = GetURL(db, "", newSlug)
+	assert.Nil(t, err)
+
+	stats, err := GetURLStats(db, map[string]interface{}{
+		"slug": newSlug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stats))
+
+	// Test GetURLAlias
+	alias, err := GetURLAlias(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, newSlug, alias.Slug)
+
+	// Test renaming back to the former slug
+	err = RenameURL(db, "", newSlug, slug)
+	assert.Nil(t, err)
+
+	aliases, err := GetURLAliases(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(aliases))
+	assert.Equal(t, newSlug, aliases[0].Alias)
+}
+
+func TestEditAndRenameURL(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	taken := models.GenerateSlug(7)
+	for _, s := range []string{slug, taken} {
+		err := CreateURL(db, &models.URL{
+			Url:  "https://example.com",
+			Slug: s,
+		})
+		assert.Nil(t, err)
+	}
+
+	url, err := GetURL(db, "", slug)
+	assert.Nil(t, err)
+
+	// the edit is not kept when the rename fails
+	url.Url = "https://example.org"
+	err = EditAndRenameURL(db, url, taken)
+	assert.True(t, IsUniqueViolation(err))
+
+	url, err = GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com", url.Url)
+
+	url.Url = "https://example.org"
+	newSlug := models.GenerateSlug(8)
+	err = EditAndRenameURL(db, url, newSlug)
+	assert.Nil(t, err)
+
+	url, err = GetURL(db, "", newSlug)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.org", url.Url)
+}
@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLBotStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLBotStat, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_bot_stats").OrderBy("counter desc", "reason")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.URLBotStat
+
+	if err := db.Select(&stats, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func UpsertURLBotStat(db *sqlx.DB, stat *models.URLBotStat) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_bot_stats").Columns("domain, slug, reason, counter, created, updated").Values(
+		stat.Domain, stat.Slug, stat.Reason, stat.Counter, stat.Created, stat.Updated).
+		Suffix(`ON CONFLICT (domain, slug, reason) DO UPDATE SET counter = url_bot_stats.counter + 1, updated = NOW()`)
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLBotStat(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	for _, reason := range []string{"user_agent", "head", "user_agent"} {
+		err := UpsertURLBotStat(db, &models.URLBotStat{
+			Slug:    slug,
+			Reason:  reason,
+			Counter: 1,
+			Created: time.Now(),
+		})
+		assert.Nil(t, err)
+	}
+
+	stats, err := GetURLBotStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "user_agent", stats[0].Reason)
+	assert.Equal(t, 2, stats[0].Counter)
+	assert.Equal(t, "head", stats[1].Reason)
+	assert.Equal(t, 1, stats[1].Counter)
+}
@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLCampaignStat(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	for _, campaign := range []string{"spring-sale", "launch", "spring-sale"} {
+		err := UpsertURLCampaignStat(db, &models.URLCampaignStat{
+			Slug:     slug,
+			Campaign: campaign,
+			Counter:  1,
+			Created:  time.Now(),
+		})
+		assert.Nil(t, err)
+	}
+
+	stats, err := GetURLCampaignStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "spring-sale", stats[0].Campaign)
+	assert.Equal(t, 2, stats[0].Counter)
+	assert.Equal(t, "launch", stats[1].Campaign)
+	assert.Equal(t, 1, stats[1].Counter)
+}
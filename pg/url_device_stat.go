@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLDeviceStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLDeviceStat, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_device_stats").OrderBy("counter desc", "device", "os", "browser")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.URLDeviceStat
+
+	if err := db.Select(&stats, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func UpsertURLDeviceStat(db *sqlx.DB, stat *models.URLDeviceStat) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_device_stats").Columns("domain, slug, browser, os, device, counter, created, updated").Values(
+		stat.Domain, stat.Slug, stat.Browser, stat.OS, stat.Device, stat.Counter, stat.Created, stat.Updated).
+		Suffix(`ON CONFLICT (domain, slug, browser, os, device) DO UPDATE SET counter = url_device_stats.counter + 1, updated = NOW()`)
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
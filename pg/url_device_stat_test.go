@@ -0,0 +1,39 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLDeviceStat(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	for _, stat := range []models.URLDeviceStat{
+		{Browser: "Chrome", OS: "Android", Device: "Mobile"},
+		{Browser: "Firefox", OS: "Windows", Device: "Desktop"},
+		{Browser: "Chrome", OS: "Android", Device: "Mobile"},
+	} {
+		stat.Slug = slug
+		stat.Counter = 1
+		stat.Created = time.Now()
+		err := UpsertURLDeviceStat(db, &stat)
+		assert.Nil(t, err)
+	}
+
+	stats, err := GetURLDeviceStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "Chrome", stats[0].Browser)
+	assert.Equal(t, "Android", stats[0].OS)
+	assert.Equal(t, "Mobile", stats[0].Device)
+	assert.Equal(t, 2, stats[0].Counter)
+	assert.Equal(t, "Firefox", stats[1].Browser)
+	assert.Equal(t, 1, stats[1].Counter)
+}
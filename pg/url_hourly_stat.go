@@ -0,0 +1,63 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetURLHourlyStats returns the hourly clicks of a url in order. "from" and
+// "to" limit them to a time range.
+func GetURLHourlyStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLHourlyStat, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_hourly_stats").OrderBy("hour")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	if from, ok := clauses["from"].(time.Time); ok {
+		sb = sb.Where("hour >= ?", from.UTC())
+	}
+
+	if to, ok := clauses["to"].(time.Time); ok {
+		sb = sb.Where("hour < ?", to.UTC())
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.URLHourlyStat
+
+	if err := db.Select(&stats, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// UpsertURLHourlyStat adds the clicks of stat to the hour they were made in
+func UpsertURLHourlyStat(db *sqlx.DB, stat *models.URLHourlyStat) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_hourly_stats").Columns("domain, slug, hour, counter").Values(
+		stat.Domain, stat.Slug, stat.Hour.UTC().Truncate(time.Hour), stat.Counter).
+		Suffix(`ON CONFLICT (domain, slug, hour) DO UPDATE SET counter = url_hourly_stats.counter + EXCLUDED.counter`)
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLHourlyStat(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	hour := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	for _, clicked := range []time.Time{hour.Add(5 * time.Minute), hour.Add(55 * time.Minute), hour.Add(2 * time.Hour)} {
+		err := UpsertURLHourlyStat(db, &models.URLHourlyStat{
+			Slug:    slug,
+			Hour:    clicked,
+			Counter: 1,
+		})
+		assert.Nil(t, err)
+	}
+
+	stats, err := GetURLHourlyStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.True(t, hour.Equal(stats[0].Hour))
+	assert.Equal(t, 2, stats[0].Counter)
+
+	stats, err = GetURLHourlyStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+		"from":   hour.Add(time.Hour),
+		"to":     hour.Add(3 * time.Hour),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, 1, stats[0].Counter)
+}
@@ -0,0 +1,65 @@
+package pg
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLRevisions(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLRevision, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_revisions").OrderBy("created desc, id desc")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	if action, ok := clauses["action"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"action": action})
+	}
+
+	if limit, ok := clauses["_limit"].(uint64); ok {
+		sb = sb.Limit(limit)
+	}
+
+	if offset, ok := clauses["_offset"].(uint64); ok {
+		sb = sb.Offset(offset)
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []models.URLRevision
+	if err := db.Select(&revisions, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func CreateURLRevision(db *sqlx.DB, revision *models.URLRevision) error {
+	if revision.OldValues == nil {
+		revision.OldValues = models.PropertyMap{}
+	}
+	if revision.NewValues == nil {
+		revision.NewValues = models.PropertyMap{}
+	}
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_revisions").Columns("domain, slug, action, username, source, old_values, new_values, created").
+		Values(revision.Domain, revision.Slug, revision.Action, revision.Username, revision.Source, revision.OldValues, revision.NewValues, revision.Created)
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLRevision(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	revision := &models.URLRevision{
+		Slug:      slug,
+		Action:    models.RevisionUpdate,
+		Username:  "someone",
+		Source:    models.SourceWeb,
+		OldValues: models.PropertyMap{"url": "https://example.com"},
+		NewValues: models.PropertyMap{"url": "https://example.org"},
+		Created:   time.Now(),
+	}
+
+	// Test CreateURLRevision
+	err := CreateURLRevision(db, revision)
+	assert.Nil(t, err)
+
+	// Test GetURLRevisions
+	revisions, err := GetURLRevisions(db, map[string]interface{}{
+		"slug": slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(revisions))
+	assert.Equal(t, "https://example.org", revisions[0].NewValues["url"])
+	assert.Equal(t, models.SourceWeb, revisions[0].Source)
+}
@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLSchedules(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLSchedule, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_schedules").OrderBy("scheduled, id")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	if pending, ok := clauses["pending"].(bool); ok {
+		if pending {
+			sb = sb.Where(squirrel.Eq{"applied": nil})
+		} else {
+			sb = sb.Where(squirrel.NotEq{"applied": nil})
+		}
+	}
+
+	if due, ok := clauses["due"].(time.Time); ok {
+		sb = sb.Where("scheduled <= ?", due)
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []models.URLSchedule
+	if err := db.Select(&schedules, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func CreateURLSchedule(db *sqlx.DB, schedule *models.URLSchedule) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_schedules").Columns("domain, slug, url, scheduled, username, created").
+		Values(schedule.Domain, schedule.Slug, schedule.URL, schedule.Scheduled, schedule.Username, schedule.Created).
+		Suffix("RETURNING id")
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return db.Get(&schedule.ID, sqlStr, args...)
+}
+
+// DeleteURLSchedule removes a pending change of a url. It returns false if
+// there is none with the id.
+func DeleteURLSchedule(db *sqlx.DB, domain, slug string, id int64) (bool, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Delete("url_schedules").
+		Where(squirrel.Eq{"id": id, "domain": domain, "slug": slug, "applied": nil})
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Exec(sqlStr, args...)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ApplyURLSchedule changes the destination of the url of a schedule and marks
+// the schedule applied in one transaction. It returns the url before the
+// change, or nil if the schedule was already applied or the url is gone.
+func ApplyURLSchedule(db *sqlx.DB, schedule *models.URLSchedule) (*models.URL, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE url_schedules SET applied = NOW() WHERE id = $1 AND applied IS NULL", schedule.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count, err := result.RowsAffected(); err != nil || count == 0 {
+		return nil, err
+	}
+
+	var urls []models.URL
+	if err := tx.Select(&urls, "SELECT * FROM urls WHERE domain = $1 AND slug = $2 AND deleted IS NULL FOR UPDATE",
+		schedule.Domain, schedule.Slug); err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, tx.Commit()
+	}
+
+	// a new destination gets a fresh spam scan
+	if _, err := tx.Exec(`UPDATE urls SET url = $1, updated = NOW(),
+		status = CASE WHEN status IN ('pending', 'expired') THEN status ELSE 'active' END
+		WHERE domain = $2 AND slug = $3`, schedule.URL, schedule.Domain, schedule.Slug); err != nil {
+		return nil, err
+	}
+	return &urls[0], tx.Commit()
+}
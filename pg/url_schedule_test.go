@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLSchedule(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:  "https://example.com",
+		Slug: slug,
+	})
+	assert.Nil(t, err)
+
+	due := &models.URLSchedule{
+		Slug:      slug,
+		URL:       "https://example.org",
+		Scheduled: time.Now().Add(-time.Minute),
+		Created:   time.Now(),
+	}
+	later := &models.URLSchedule{
+		Slug:      slug,
+		URL:       "https://example.net",
+		Scheduled: time.Now().Add(time.Hour),
+		Created:   time.Now(),
+	}
+
+	// Test CreateURLSchedule
+	err = CreateURLSchedule(db, due)
+	assert.Nil(t, err)
+	assert.NotZero(t, due.ID)
+	err = CreateURLSchedule(db, later)
+	assert.Nil(t, err)
+
+	// Test GetURLSchedules
+	schedules, err := GetURLSchedules(db, map[string]interface{}{
+		"slug":    slug,
+		"pending": true,
+		"due":     time.Now(),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(schedules))
+	assert.Equal(t, due.ID, schedules[0].ID)
+
+	// Test ApplyURLSchedule
+	url, err := ApplyURLSchedule(db, &schedules[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com", url.Url)
+	returnedUrl, err := GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.org", returnedUrl.Url)
+
+	// applying twice does nothing
+	url, err = ApplyURLSchedule(db, &schedules[0])
+	assert.Nil(t, err)
+	assert.Nil(t, url)
+
+	// Test DeleteURLSchedule
+	deleted, err := DeleteURLSchedule(db, "", slug, due.ID)
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+	deleted, err = DeleteURLSchedule(db, "", slug, later.ID)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+}
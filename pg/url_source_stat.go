@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLSourceStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLSourceStat, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_source_stats").OrderBy("counter desc", "source", "medium")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.URLSourceStat
+
+	if err := db.Select(&stats, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func UpsertURLSourceStat(db *sqlx.DB, stat *models.URLSourceStat) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_source_stats").Columns("domain, slug, source, medium, counter, created, updated").Values(
+		stat.Domain, stat.Slug, stat.Source, stat.Medium, stat.Counter, stat.Created, stat.Updated).
+		Suffix(`ON CONFLICT (domain, slug, source, medium) DO UPDATE SET counter = url_source_stats.counter + 1, updated = NOW()`)
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLSourceStat(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	for _, source := range []string{"qr", "newsletter", "qr"} {
+		err := UpsertURLSourceStat(db, &models.URLSourceStat{
+			Slug:    slug,
+			Source:  source,
+			Medium:  source,
+			Counter: 1,
+			Created: time.Now(),
+		})
+		assert.Nil(t, err)
+	}
+
+	stats, err := GetURLSourceStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "qr", stats[0].Source)
+	assert.Equal(t, "qr", stats[0].Medium)
+	assert.Equal(t, 2, stats[0].Counter)
+	assert.Equal(t, "newsletter", stats[1].Source)
+	assert.Equal(t, 1, stats[1].Counter)
+}
@@ -11,6 +11,10 @@ func GetURLStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLStat,
 	sb := psql.Select("*").
 		From("url_stats").OrderBy("created desc")
 
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
 	if slug, ok := clauses["slug"].(string); ok {
 		sb = sb.Where(squirrel.Eq{"slug": slug})
 	}
@@ -38,9 +42,9 @@ func GetURLStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLStat,
 
 func UpsertURLStat(db *sqlx.DB, stat *models.URLStat) error {
 	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
-	sb := psql.Insert("url_stats").Columns("slug, country, state, counter, properties, created, updated").Values(
-		stat.Slug, stat.Country, stat.State, stat.Counter, stat.Properties, stat.Created, stat.Updated).
-		Suffix(`ON CONFLICT ON CONSTRAINT urls_stats_slug_country_state_pkey DO UPDATE SET counter = url_stats.counter + 1, updated = NOW()`)
+	sb := psql.Insert("url_stats").Columns("domain, slug, country, state, counter, properties, created, updated").Values(
+		stat.Domain, stat.Slug, stat.Country, stat.State, stat.Counter, stat.Properties, stat.Created, stat.Updated).
+		Suffix(`ON CONFLICT ON CONSTRAINT url_stats_domain_slug_country_state_key DO UPDATE SET counter = url_stats.counter + 1, updated = NOW()`)
 
 	sqlStr, args, err := sb.ToSql()
 	if err != nil {
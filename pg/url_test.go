@@ -1,8 +1,11 @@
 package pg
 
 import (
+	"database/sql"
 	"testing"
+	"time"
 
+	"github.com/guregu/null"
 	"github.com/jasontthai/tinyalias/models"
 	"github.com/jasontthai/tinyalias/test"
 	"github.com/jmoiron/sqlx"
@@ -30,7 +33,7 @@ func TestURL(t *testing.T) {
 	assert.Nil(t, err)
 
 	// Test GetURL
-	returnedUrl, err := GetURL(db, slug)
+	returnedUrl, err := GetURL(db, "", slug)
 	assert.Nil(t, err)
 	assert.Equal(t, url.Url, returnedUrl.Url)
 
@@ -46,3 +49,208 @@ func TestURL(t *testing.T) {
 	err = UpdateURL(db, url)
 	assert.Nil(t, err)
 }
+
+func TestTrashURL(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:  "https://example.com",
+		Slug: slug,
+	})
+	assert.Nil(t, err)
+
+	// Test TrashURL
+	err = TrashURL(db, "", slug)
+	assert.Nil(t, err)
+
+	returnedUrl, err := GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.True(t, returnedUrl.Deleted.Valid)
+
+	// urls in the trash are only listed when asked for
+	returnedUrls, err := GetURLs(db, map[string]interface{}{
+		"slug": slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(returnedUrls))
+
+	returnedUrls, err = GetURLs(db, map[string]interface{}{
+		"slug":    slug,
+		"deleted": true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(returnedUrls))
+
+	// Test RestoreURL
+	err = RestoreURL(db, "", slug)
+	assert.Nil(t, err)
+
+	returnedUrl, err = GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.False(t, returnedUrl.Deleted.Valid)
+
+	// Test PurgeTrashedURLs
+	err = TrashURL(db, "", slug)
+	assert.Nil(t, err)
+	err = CreateURLRevision(db, &models.URLRevision{
+		Slug:    slug,
+		Action:  models.RevisionDelete,
+		Created: time.Now(),
+	})
+	assert.Nil(t, err)
+
+	purged, err := PurgeTrashedURLs(db, time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, len(purged))
+
+	_, err = GetURL(db, "", slug)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	revisions, err := GetURLRevisions(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(revisions))
+}
+
+func TestClickURL(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:       "https://example.com",
+		Slug:      slug,
+		Status:    models.Pending,
+		MaxClicks: 3,
+	})
+	assert.Nil(t, err)
+
+	// concurrent clicks never go over the limit
+	results := make(chan error)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := ClickURL(db, "", slug)
+			results <- err
+		}()
+	}
+	var clicks int
+	for i := 0; i < 10; i++ {
+		err := <-results
+		if err == nil {
+			clicks++
+		} else {
+			assert.Equal(t, sql.ErrNoRows, err)
+		}
+	}
+	assert.Equal(t, 3, clicks)
+
+	url, err := GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, url.Counter)
+	assert.Equal(t, models.Active, url.Status)
+	assert.True(t, url.IsExhausted())
+}
+
+func TestUpdateURLMetadata(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:  "https://example.com",
+		Slug: slug,
+	})
+	assert.Nil(t, err)
+
+	url, err := GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.False(t, url.Metadata.Fetched.Valid)
+
+	err = UpdateURLMetadata(db, "", slug, models.Metadata{
+		Title:   "Example Domain",
+		Favicon: "https://example.com/favicon.ico",
+		Fetched: null.TimeFrom(time.Now()),
+	})
+	assert.Nil(t, err)
+
+	url, err = GetURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, "Example Domain", url.Metadata.Title)
+	assert.Equal(t, "https://example.com/favicon.ico", url.Metadata.Favicon)
+	assert.True(t, url.Metadata.Fetched.Valid)
+	assert.False(t, url.Updated.Valid)
+}
+
+func TestDeletePendingURLs(t *testing.T) {
+	db := setup(t)
+
+	pending := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:    "https://example.com",
+		Slug:   pending,
+		Status: models.Pending,
+	})
+	assert.Nil(t, err)
+	err = CreateURLRevision(db, &models.URLRevision{
+		Slug:      pending,
+		Action:    models.RevisionCreate,
+		Username:  "someone",
+		Source:    models.SourceWeb,
+		NewValues: models.PropertyMap{"url": "https://example.com"},
+		Created:   time.Now(),
+	})
+	assert.Nil(t, err)
+
+	scheduled := models.GenerateSlug(6)
+	err = CreateURL(db, &models.URL{
+		Url:       "https://example.com",
+		Slug:      scheduled,
+		Status:    models.Pending,
+		NotBefore: null.TimeFrom(time.Now().Add(time.Hour)),
+	})
+	assert.Nil(t, err)
+
+	_, err = DeletePendingURLs(db)
+	assert.Nil(t, err)
+
+	// pending urls are gone but scheduled ones wait for their launch
+	_, err = GetURL(db, "", pending)
+	assert.Equal(t, sql.ErrNoRows, err)
+	url, err := GetURL(db, "", scheduled)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Pending, url.Status)
+
+	// the next owner of the slug does not see the history of the deleted url
+	err = CreateURL(db, &models.URL{
+		Url:  "https://example.org",
+		Slug: pending,
+	})
+	assert.Nil(t, err)
+	revisions, err := GetURLRevisions(db, map[string]interface{}{
+		"slug": pending,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(revisions))
+}
+
+func TestActivateURL(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	err := CreateURL(db, &models.URL{
+		Url:    "https://example.com",
+		Slug:   slug,
+		Status: models.Pending,
+	})
+	assert.Nil(t, err)
+
+	url, err := ActivateURL(db, "", slug)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Active, url.Status)
+	assert.Equal(t, 0, url.Counter)
+
+	// only pending urls are activated
+	_, err = ActivateURL(db, "", slug)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func GetURLVariantStats(db *sqlx.DB, clauses map[string]interface{}) ([]models.URLVariantStat, error) {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Select("*").
+		From("url_variant_stats").OrderBy("variant")
+
+	if domain, ok := clauses["domain"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"domain": domain})
+	}
+
+	if slug, ok := clauses["slug"].(string); ok {
+		sb = sb.Where(squirrel.Eq{"slug": slug})
+	}
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.URLVariantStat
+
+	if err := db.Select(&stats, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func UpsertURLVariantStat(db *sqlx.DB, stat *models.URLVariantStat) error {
+	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+	sb := psql.Insert("url_variant_stats").Columns("domain, slug, variant, counter, created, updated").Values(
+		stat.Domain, stat.Slug, stat.Variant, stat.Counter, stat.Created, stat.Updated).
+		Suffix(`ON CONFLICT (domain, slug, variant) DO UPDATE SET counter = url_variant_stats.counter + 1, updated = NOW()`)
+
+	sqlStr, args, err := sb.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(sqlStr, args...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasontthai/tinyalias/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLVariantStat(t *testing.T) {
+	db := setup(t)
+
+	slug := models.GenerateSlug(6)
+	for _, variant := range []string{"B", "A", "B"} {
+		err := UpsertURLVariantStat(db, &models.URLVariantStat{
+			Slug:    slug,
+			Variant: variant,
+			Counter: 1,
+			Created: time.Now(),
+		})
+		assert.Nil(t, err)
+	}
+
+	stats, err := GetURLVariantStats(db, map[string]interface{}{
+		"domain": "",
+		"slug":   slug,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "A", stats[0].Variant)
+	assert.Equal(t, 1, stats[0].Counter)
+	assert.Equal(t, "B", stats[1].Variant)
+	assert.Equal(t, 2, stats[1].Counter)
+}
@@ -67,4 +67,268 @@ CREATE TABLE IF NOT EXISTS users (
 ALTER TABLE urls
   ADD COLUMN username text NOT NULL DEFAULT '';
 
-CREATE INDEX idx_username ON urls USING btree (username);
\ No newline at end of file
+CREATE INDEX idx_username ON urls USING btree (username);
+CREATE TABLE IF NOT EXISTS api_keys (
+  id serial PRIMARY KEY,
+  username text NOT NULL,
+  name text NOT NULL DEFAULT '',
+  prefix text NOT NULL,
+  hash text NOT NULL,
+  scopes text[] NOT NULL DEFAULT '{}',
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  last_used timestamp without time zone,
+  revoked timestamp without time zone
+);
+
+ALTER TABLE api_keys
+  ADD CONSTRAINT api_keys_hash_key UNIQUE (hash);
+
+CREATE INDEX idx_api_keys_username ON api_keys USING btree (username);
+
+CREATE TABLE IF NOT EXISTS url_aliases (
+  alias text NOT NULL PRIMARY KEY,
+  slug text NOT NULL REFERENCES urls (slug) ON UPDATE CASCADE ON DELETE CASCADE,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL
+);
+
+CREATE INDEX idx_url_aliases_slug ON url_aliases USING btree (slug);
+
+CREATE TABLE IF NOT EXISTS url_revisions (
+  id serial PRIMARY KEY,
+  slug text NOT NULL,
+  action text NOT NULL,
+  username text NOT NULL DEFAULT '',
+  source text NOT NULL,
+  old_values jsonb NOT NULL DEFAULT '{}'::jsonb,
+  new_values jsonb NOT NULL DEFAULT '{}'::jsonb,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL
+);
+
+CREATE INDEX idx_url_revisions_slug ON url_revisions USING btree (slug);
+
+ALTER TABLE urls
+  ADD COLUMN deleted timestamp without time zone;
+
+CREATE INDEX idx_deleted ON urls USING btree (deleted);
+
+-- starts at 58^3 so sequential slugs are at least 4 characters long
+CREATE SEQUENCE IF NOT EXISTS url_slug_seq START 195112;
+
+CREATE TABLE IF NOT EXISTS reserved_slugs (
+  slug text NOT NULL PRIMARY KEY,
+  reason text NOT NULL,
+  username text NOT NULL DEFAULT '',
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL
+);
+
+INSERT INTO reserved_slugs (slug, reason) VALUES
+  ('admin', 'brand'),
+  ('support', 'brand'),
+  ('help', 'brand'),
+  ('tinyalias', 'brand'),
+  ('official', 'brand'),
+  ('fuck', 'profanity'),
+  ('shit', 'profanity'),
+  ('bitch', 'profanity'),
+  ('cunt', 'profanity'),
+  ('porn', 'profanity')
+ON CONFLICT (slug) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS custom_domains (
+  host text NOT NULL PRIMARY KEY,
+  username text NOT NULL,
+  token text NOT NULL,
+  verified timestamp without time zone,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL
+);
+
+CREATE INDEX idx_custom_domains_username ON custom_domains USING btree (username);
+
+-- slugs are unique per domain, '' being the default domain of BASE_URL
+ALTER TABLE urls
+  ADD COLUMN domain text NOT NULL DEFAULT '';
+
+ALTER TABLE url_aliases
+  DROP CONSTRAINT url_aliases_slug_fkey;
+
+ALTER TABLE urls
+  DROP CONSTRAINT urls_slug_key;
+
+ALTER TABLE urls
+  ADD CONSTRAINT urls_domain_slug_key UNIQUE (domain, slug);
+
+ALTER TABLE url_aliases
+  ADD COLUMN domain text NOT NULL DEFAULT '';
+
+ALTER TABLE url_aliases
+  DROP CONSTRAINT url_aliases_pkey;
+
+ALTER TABLE url_aliases
+  ADD PRIMARY KEY (domain, alias);
+
+ALTER TABLE url_aliases
+  ADD CONSTRAINT url_aliases_domain_slug_fkey FOREIGN KEY (domain, slug)
+  REFERENCES urls (domain, slug) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE url_stats
+  ADD COLUMN domain text NOT NULL DEFAULT '';
+
+ALTER TABLE url_stats
+  DROP CONSTRAINT urls_stats_slug_country_state_pkey;
+
+ALTER TABLE url_stats
+  ADD CONSTRAINT url_stats_domain_slug_country_state_key UNIQUE (domain, slug, country, state);
+
+ALTER TABLE url_revisions
+  ADD COLUMN domain text NOT NULL DEFAULT '';
+
+ALTER TABLE urls
+  ADD COLUMN forward_query text NOT NULL DEFAULT '';
+
+ALTER TABLE urls
+  ADD COLUMN forward_path boolean NOT NULL DEFAULT false;
+
+ALTER TABLE urls
+  ADD COLUMN targets jsonb NOT NULL DEFAULT '[]'::jsonb;
+
+ALTER TABLE urls
+  ADD COLUMN geo_targets jsonb NOT NULL DEFAULT '[]'::jsonb;
+
+ALTER TABLE urls
+  ADD COLUMN variants jsonb NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE TABLE IF NOT EXISTS url_variant_stats (
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  variant text NOT NULL,
+  counter integer NOT NULL DEFAULT 0,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  updated timestamp without time zone,
+  UNIQUE (domain, slug, variant)
+);
+
+ALTER TABLE urls
+  ADD COLUMN max_clicks integer NOT NULL DEFAULT 0;
+
+ALTER TABLE urls
+  ADD COLUMN not_before timestamp without time zone;
+
+CREATE TABLE IF NOT EXISTS url_schedules (
+  id serial PRIMARY KEY,
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  url text NOT NULL,
+  scheduled timestamp without time zone NOT NULL,
+  applied timestamp without time zone,
+  username text NOT NULL DEFAULT '',
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  FOREIGN KEY (domain, slug) REFERENCES urls (domain, slug) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_url_schedules_pending ON url_schedules USING btree (scheduled) WHERE applied IS NULL;
+
+ALTER TABLE urls
+  ADD COLUMN redirect_code integer NOT NULL DEFAULT 0,
+  ADD COLUMN cache_control text NOT NULL DEFAULT '',
+  ADD COLUMN no_index boolean NOT NULL DEFAULT false,
+  ADD COLUMN referrer_policy text NOT NULL DEFAULT '';
+
+ALTER TABLE urls
+  ADD COLUMN description text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS url_source_stats (
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  source text NOT NULL,
+  counter integer NOT NULL DEFAULT 0,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  updated timestamp without time zone,
+  UNIQUE (domain, slug, source)
+);
+
+ALTER TABLE urls
+  ADD COLUMN metadata jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE urls
+  ADD COLUMN card jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS click_events (
+  id bigserial PRIMARY KEY,
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  clicked timestamp without time zone NOT NULL,
+  ip_hash text NOT NULL DEFAULT '',
+  referrer text NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  language text NOT NULL DEFAULT '',
+  variant text NOT NULL DEFAULT '',
+  source text NOT NULL DEFAULT '',
+  country text NOT NULL DEFAULT '',
+  state text NOT NULL DEFAULT '',
+  FOREIGN KEY (domain, slug) REFERENCES urls (domain, slug) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_click_events_url ON click_events USING btree (domain, slug, clicked);
+
+CREATE TABLE IF NOT EXISTS url_hourly_stats (
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  hour timestamp without time zone NOT NULL,
+  counter integer NOT NULL DEFAULT 0,
+  PRIMARY KEY (domain, slug, hour)
+);
+
+INSERT INTO url_hourly_stats (domain, slug, hour, counter)
+  SELECT domain, slug, date_trunc('hour', clicked), count(*) FROM click_events GROUP BY 1, 2, 3
+  ON CONFLICT DO NOTHING;
+
+ALTER TABLE url_source_stats
+  ADD COLUMN medium text NOT NULL DEFAULT '',
+  DROP CONSTRAINT url_source_stats_domain_slug_source_key,
+  ADD UNIQUE (domain, slug, source, medium);
+
+UPDATE url_source_stats SET medium = 'qr' WHERE source = 'qr';
+
+ALTER TABLE click_events
+  ADD COLUMN medium text NOT NULL DEFAULT '',
+  ADD COLUMN campaign text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS url_campaign_stats (
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  campaign text NOT NULL,
+  counter integer NOT NULL DEFAULT 0,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  updated timestamp without time zone,
+  UNIQUE (domain, slug, campaign)
+);
+
+ALTER TABLE click_events
+  ADD COLUMN browser text NOT NULL DEFAULT '',
+  ADD COLUMN os text NOT NULL DEFAULT '',
+  ADD COLUMN device text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS url_device_stats (
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  browser text NOT NULL,
+  os text NOT NULL,
+  device text NOT NULL,
+  counter integer NOT NULL DEFAULT 0,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  updated timestamp without time zone,
+  UNIQUE (domain, slug, browser, os, device)
+);
+
+ALTER TABLE click_events
+  ADD COLUMN bot text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS url_bot_stats (
+  domain text NOT NULL DEFAULT '',
+  slug text NOT NULL,
+  reason text NOT NULL,
+  counter integer NOT NULL DEFAULT 0,
+  created timestamp without time zone DEFAULT timezone('utc'::text, now()) NOT NULL,
+  updated timestamp without time zone,
+  UNIQUE (domain, slug, reason)
+);